@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/v2box"
+
+	"github.com/spf13/cobra"
+)
+
+var commandDetect = &cobra.Command{
+	Use:   "detect",
+	Short: "Detect the type of your v2ray configuration.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := detect()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	command.AddCommand(commandDetect)
+}
+
+func detect() error {
+	content, _, err := readConfig()
+	if err != nil {
+		return err
+	}
+	detections := v2box.Detect(content)
+	if len(detections) == 0 {
+		return E.New("no configuration type registered")
+	}
+	fmt.Println("type:", detections[0].TypeName)
+	for _, detection := range detections {
+		fmt.Println()
+		fmt.Println(detection.TypeName, "score:", detection.Score)
+		for _, reason := range detection.Reasons {
+			fmt.Println("  ", reason)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/v2box"
+
+	"github.com/spf13/cobra"
+)
+
+var commandExport = &cobra.Command{
+	Use:   "export",
+	Short: "Export your sing-box configuration into v2ray.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := export()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandExport.Flags().StringVarP(&reportFormat, "report", "r", "", "print export report (text, json)")
+	commandExport.Flags().StringVar(&reportOutput, "report-output", "stderr", "export report output path")
+	command.AddCommand(commandExport)
+}
+
+func export() error {
+	if configDirectory != "" || len(configPaths) > 1 {
+		return E.New("export reads a single sing-box configuration file")
+	}
+	path := "config.json"
+	if len(configPaths) == 1 {
+		path = configPaths[0]
+	}
+	content, lines, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	var options option.Options
+	err = v2box.DecodeJSON(content, &options)
+	if err != nil {
+		return E.Cause(lines.LocateError(content, err), "decode config")
+	}
+	content, report, err := v2box.Export(configType, options, log.StdLogger())
+	if err != nil {
+		return E.Cause(err, "export config")
+	}
+	if lines != nil {
+		report.Locate(lines)
+	}
+	err = writeReport(report)
+	if err != nil {
+		return E.Cause(err, "write report")
+	}
+	if strictMode {
+		err = report.Err()
+		if err != nil {
+			return E.Cause(err, "strict mode")
+		}
+	}
+	_, err = os.Stdout.Write(append(content, '\n'))
+	return err
+}
@@ -13,6 +13,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	reportFormat string
+	reportOutput string
+)
+
 var commandMigrate = &cobra.Command{
 	Use:   "migrate",
 	Short: "Migrate your v2ray configuration into sing-box.",
@@ -26,31 +31,64 @@ var commandMigrate = &cobra.Command{
 }
 
 func init() {
+	commandMigrate.Flags().StringVarP(&reportFormat, "report", "r", "", "print migration report (text, json)")
+	commandMigrate.Flags().StringVar(&reportOutput, "report-output", "stderr", "migration report output path")
 	command.AddCommand(commandMigrate)
 }
 
 func migrate() error {
 	var (
 		options option.Options
-		content []byte
-		err     error
+		report  v2box.Report
 	)
-	if configPath == "stdin" {
-		content, err = io.ReadAll(os.Stdin)
-	} else {
-		content, err = os.ReadFile(configPath)
-	}
+	content, lines, err := readConfig()
 	if err != nil {
-		return E.Cause(err, "read config")
+		return err
 	}
-	options, err = v2box.Migrate(configType, content, log.StdLogger())
+	options, report, err = v2box.Migrate(configType, content, v2box.MigrateOptions{DisableInjection: disableInjection, MaxInboundPorts: maxInboundPorts}, log.StdLogger())
 	if err != nil {
-		return E.Cause(err, "load config")
+		return E.Cause(lines.LocateError(content, err), "load config")
 	}
+	if lines != nil {
+		report.Locate(lines)
+	}
+	err = writeReport(report)
 	if err != nil {
-		return err
+		return E.Cause(err, "write report")
+	}
+	if strictMode {
+		err = report.Err()
+		if err != nil {
+			return E.Cause(err, "strict mode")
+		}
 	}
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
 	return encoder.Encode(options)
 }
+
+func writeReport(report v2box.Report) error {
+	if reportFormat == "" {
+		report.Log(log.StdLogger())
+		return nil
+	}
+	var writer io.Writer
+	if reportOutput == "stderr" {
+		writer = os.Stderr
+	} else {
+		file, err := os.Create(reportOutput)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+	switch reportFormat {
+	case "text":
+		return report.WriteText(writer)
+	case "json":
+		return report.WriteJSON(writer)
+	default:
+		return E.New("unknown report format: ", reportFormat)
+	}
+}
@@ -41,7 +41,7 @@ func migrateGeoIP() error {
 		content []byte
 		err     error
 	)
-	if configPath == "stdin" {
+	if geoipInput == "stdin" {
 		content, err = io.ReadAll(os.Stdin)
 	} else {
 		content, err = os.ReadFile(geoipInput)
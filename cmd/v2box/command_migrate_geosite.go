@@ -41,7 +41,7 @@ func migrateGeoSite() error {
 		content []byte
 		err     error
 	)
-	if configPath == "stdin" {
+	if geositeInput == "stdin" {
 		content, err = io.ReadAll(os.Stdin)
 	} else {
 		content, err = os.ReadFile(geositeInput)
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"io"
 	"os"
 	"os/signal"
 	"runtime/debug"
@@ -35,23 +34,25 @@ func init() {
 func run() error {
 	var (
 		options option.Options
-		content []byte
-		err     error
+		report  v2box.Report
 	)
-	if configPath == "stdin" {
-		content, err = io.ReadAll(os.Stdin)
-	} else {
-		content, err = os.ReadFile(configPath)
-	}
+	content, lines, err := readConfig()
 	if err != nil {
-		return E.Cause(err, "read config")
+		return err
 	}
-	options, err = v2box.Migrate(configType, content, log.StdLogger())
+	options, report, err = v2box.Migrate(configType, content, v2box.MigrateOptions{DisableInjection: disableInjection, MaxInboundPorts: maxInboundPorts}, log.StdLogger())
 	if err != nil {
-		return E.Cause(err, "load config")
+		return E.Cause(lines.LocateError(content, err), "load config")
 	}
-	if err != nil {
-		return err
+	if lines != nil {
+		report.Locate(lines)
+	}
+	report.Log(log.StdLogger())
+	if strictMode {
+		err = report.Err()
+		if err != nil {
+			return E.Cause(err, "strict mode")
+		}
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	instance, err := box.New(ctx, options, nil)
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/v2box"
+)
+
+// readConfig reads all configuration files given by --config and --confdir,
+// converts them to JSON and merges them with the rules of the configuration type.
+// The returned LineMap locates report entries in the original file, it is only available for a single YAML or TOML file.
+func readConfig() ([]byte, v2box.LineMap, error) {
+	var (
+		files []v2box.ConfigFile
+		lines v2box.LineMap
+	)
+	paths := configPaths
+	if configDirectory != "" {
+		entries, err := os.ReadDir(configDirectory)
+		if err != nil {
+			return nil, nil, E.Cause(err, "read config directory")
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !isConfigFile(entry.Name()) {
+				continue
+			}
+			paths = append(paths, filepath.Join(configDirectory, entry.Name()))
+		}
+	} else if len(paths) == 0 {
+		paths = []string{"config.json"}
+	}
+	if len(paths) == 0 {
+		return nil, nil, E.New("no configuration files found in ", configDirectory)
+	}
+	for _, path := range paths {
+		var (
+			content []byte
+			err     error
+		)
+		content, lines, err = readConfigFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, v2box.ConfigFile{Name: path, Content: content})
+	}
+	content, err := v2box.Merge(configType, files)
+	if err != nil {
+		return nil, nil, E.Cause(err, "merge config")
+	}
+	if len(files) > 1 {
+		lines = nil
+	}
+	return content, lines, nil
+}
+
+// readConfigFile reads a configuration file, or stdin, and converts it to JSON.
+func readConfigFile(path string) ([]byte, v2box.LineMap, error) {
+	var (
+		content []byte
+		err     error
+	)
+	if path == "stdin" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, nil, E.Cause(err, "read config ", path)
+	}
+	format := configFormat
+	if format == v2box.FormatAuto {
+		format = v2box.FormatFromPath(path)
+	}
+	content, lines, err := v2box.ConvertFormat(format, content)
+	if err != nil {
+		return nil, nil, E.Cause(err, "read config ", path)
+	}
+	return content, lines, nil
+}
+
+func isConfigFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".jsonc", ".yaml", ".yml", ".toml":
+		return true
+	default:
+		return false
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/v2box"
 	_ "github.com/sagernet/v2box/types/v2rayjson"
 	_ "github.com/sagernet/v2box/types/xrayjson"
 
@@ -9,8 +10,13 @@ import (
 )
 
 var (
-	configType string
-	configPath string
+	configType       string
+	configFormat     string
+	configPaths      []string
+	configDirectory  string
+	strictMode       bool
+	disableInjection bool
+	maxInboundPorts  int
 )
 
 var command = &cobra.Command{
@@ -26,7 +32,12 @@ var command = &cobra.Command{
 
 func init() {
 	command.PersistentFlags().StringVarP(&configType, "type", "t", "auto", "configuration file type")
-	command.PersistentFlags().StringVarP(&configPath, "config", "c", "config.json", "configuration file path")
+	command.PersistentFlags().StringArrayVarP(&configPaths, "config", "c", nil, "configuration file path, can be repeated")
+	command.PersistentFlags().StringVar(&configDirectory, "confdir", "", "configuration directory path")
+	command.PersistentFlags().StringVar(&configFormat, "format", "auto", "configuration file format (auto, json, yaml, toml)")
+	command.PersistentFlags().BoolVar(&strictMode, "strict", false, "fail if any configuration element cannot be migrated")
+	command.PersistentFlags().BoolVar(&disableInjection, "disable-injection", false, "do not add the direct outbound and local DNS server required by migrated elements")
+	command.PersistentFlags().IntVar(&maxInboundPorts, "max-inbound-ports", v2box.DefaultMaxInboundPorts, "maximum number of inbounds a port range is expanded into")
 }
 
 func main() {
@@ -0,0 +1,94 @@
+package v2box
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/json"
+	F "github.com/sagernet/sing/common/format"
+)
+
+// Detector scores how likely a configuration is of the registered type.
+type Detector func(configuration []byte) Detection
+
+type Detection struct {
+	TypeName string   `json:"type"`
+	Score    int      `json:"score"`
+	Reasons  []string `json:"reasons,omitempty"`
+}
+
+func (d *Detection) Add(score int, reason ...any) {
+	d.Score += score
+	d.Reasons = append(d.Reasons, F.ToString(reason...))
+}
+
+func (d Detection) String() string {
+	if len(d.Reasons) == 0 {
+		return F.ToString(d.TypeName, " (score ", d.Score, ")")
+	}
+	return F.ToString(d.TypeName, " (score ", d.Score, ": ", strings.Join(d.Reasons, ", "), ")")
+}
+
+var detectorMap map[string]Detector
+
+func RegisterDetector(typeName string, detector Detector) {
+	if detectorMap == nil {
+		detectorMap = make(map[string]Detector)
+	}
+	detectorMap[typeName] = detector
+}
+
+// Detect scores the configuration against every registered migration type.
+// Results are sorted by descending score, ties are broken by type name.
+func Detect(configuration []byte) []Detection {
+	detections := make([]Detection, 0, len(migrationMap))
+	for typeName := range migrationMap {
+		var detection Detection
+		if detector, loaded := detectorMap[typeName]; loaded {
+			detection = detector(configuration)
+		}
+		detection.TypeName = typeName
+		detections = append(detections, detection)
+	}
+	sort.Slice(detections, func(i, j int) bool {
+		if detections[i].Score != detections[j].Score {
+			return detections[i].Score > detections[j].Score
+		}
+		return detections[i].TypeName < detections[j].TypeName
+	})
+	return detections
+}
+
+// WalkJSON calls walker for every object member of the configuration, in a stable order.
+// path is the JSON path of the member, e.g. outbounds[0].streamSettings.network.
+func WalkJSON(configuration []byte, walker func(path string, key string, value any)) error {
+	var content any
+	decoder := json.NewDecoder(json.NewCommentFilter(bytes.NewReader(configuration)))
+	err := decoder.Decode(&content)
+	if err != nil {
+		return err
+	}
+	walkJSON("", content, walker)
+	return nil
+}
+
+func walkJSON(path string, content any, walker func(path string, key string, value any)) {
+	switch contentType := content.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(contentType))
+		for key := range contentType {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			memberPath := JoinPath(path, key)
+			walker(memberPath, key, contentType[key])
+			walkJSON(memberPath, contentType[key], walker)
+		}
+	case []any:
+		for i, element := range contentType {
+			walkJSON(IndexPath(path, i), element, walker)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package v2box
+
+import (
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+// Exporter converts sing-box options back into a configuration of the registered type.
+// Elements that cannot be expressed by the type are recorded in the report.
+type Exporter func(options option.Options, logger logger.Logger) ([]byte, Report, error)
+
+var exporterMap map[string]Exporter
+
+func RegisterExporter(typeName string, exporter Exporter) {
+	if exporterMap == nil {
+		exporterMap = make(map[string]Exporter)
+	}
+	exporterMap[typeName] = exporter
+}
+
+func Export(typeName string, options option.Options, logger logger.Logger) ([]byte, Report, error) {
+	if typeName == "auto" {
+		return nil, Report{}, E.New("missing configuration type to export")
+	}
+	exporter, loaded := exporterMap[typeName]
+	if !loaded {
+		return nil, Report{}, E.New("exporting is not supported by configuration type: ", typeName)
+	}
+	return exporter(options, logger)
+}
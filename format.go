@@ -0,0 +1,451 @@
+package v2box
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	sjson "github.com/sagernet/sing-box/common/json"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatAuto = "auto"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// FormatFromPath returns the configuration format indicated by the file extension.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatJSON
+	}
+}
+
+// LineMap maps JSON paths of a converted configuration to lines of the original file.
+type LineMap map[string]int
+
+// Line returns the line of path, or of its closest ancestor.
+func (m LineMap) Line(path string) int {
+	for path != "" {
+		if line, loaded := m[path]; loaded {
+			return line
+		}
+		path = parentPath(path)
+	}
+	return 0
+}
+
+// LocateError prefixes a JSON decode error of the converted content with the original line.
+// Errors of nested decoders carry offsets into other content, they are located by field instead.
+func (m LineMap) LocateError(content []byte, err error) error {
+	if m == nil {
+		return err
+	}
+	var path string
+	var pathErr *PathError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &pathErr) {
+		path = pathErr.Path
+	} else if errors.As(err, &syntaxErr) {
+		path = offsetPath(content, syntaxErr.Offset)
+	} else if errors.As(err, &typeErr) && typeErr.Field != "" {
+		path = offsetPath(content, typeErr.Offset)
+		if !matchField(path, typeErr.Field) {
+			path = m.fieldPath(typeErr.Field)
+		}
+	}
+	line := m.Line(path)
+	if line == 0 {
+		return err
+	}
+	return E.Cause(err, "line ", line)
+}
+
+// fieldPath returns the only path that matches field, the dotted key path reported by nested decoders.
+func (m LineMap) fieldPath(field string) string {
+	var matched string
+	for path := range m {
+		if matchField(path, field) {
+			if matched != "" {
+				return ""
+			}
+			matched = path
+		}
+	}
+	return matched
+}
+
+func matchField(path string, field string) bool {
+	var builder strings.Builder
+	for len(path) > 0 {
+		index := strings.IndexByte(path, '[')
+		if index < 0 {
+			builder.WriteString(path)
+			break
+		}
+		builder.WriteString(path[:index])
+		end := strings.IndexByte(path[index:], ']')
+		if end < 0 {
+			break
+		}
+		path = path[index+end+1:]
+	}
+	keys := builder.String()
+	return keys == field || strings.HasSuffix(keys, "."+field)
+}
+
+// offsetPath returns the path of the JSON value whose first token ends at or after offset.
+func offsetPath(content []byte, offset int64) string {
+	type container struct {
+		path   string
+		array  bool
+		index  int
+		key    string
+		hasKey bool
+	}
+	var stack []*container
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if token == json.Delim('}') || token == json.Delim(']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		var path string
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			if parent.array {
+				path = IndexPath(parent.path, parent.index)
+				parent.index++
+			} else if !parent.hasKey {
+				parent.key, parent.hasKey = token.(string), true
+				continue
+			} else {
+				path = JoinPath(parent.path, parent.key)
+				parent.hasKey = false
+			}
+		}
+		if decoder.InputOffset() >= offset {
+			return path
+		}
+		if token == json.Delim('{') || token == json.Delim('[') {
+			stack = append(stack, &container{path: path, array: token == json.Delim('[')})
+		}
+	}
+}
+
+// DecodeJSON decodes a configuration with comments into target.
+// Errors returned by UnmarshalJSON methods carry no offset, they are wrapped in a *PathError of the failing value.
+func DecodeJSON(content []byte, target any) error {
+	decoder := json.NewDecoder(sjson.NewCommentFilter(bytes.NewReader(content)))
+	err := decoder.Decode(target)
+	if err == nil {
+		return nil
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return err
+	}
+	filtered, readErr := io.ReadAll(sjson.NewCommentFilter(bytes.NewReader(content)))
+	if readErr != nil {
+		return err
+	}
+	if path := decodePath(filtered, reflect.TypeOf(target), ""); path != "" {
+		return WrapPathError(path, err)
+	}
+	return err
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// decodePath returns the path of the innermost value of content that fails to decode into valueType,
+// or an empty string if content decodes.
+func decodePath(content []byte, valueType reflect.Type, path string) string {
+	if json.Unmarshal(content, reflect.New(valueType).Interface()) == nil {
+		return ""
+	}
+	for valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+	pointerType := reflect.PointerTo(valueType)
+	if pointerType.Implements(jsonUnmarshalerType) || pointerType.Implements(textUnmarshalerType) {
+		return path
+	}
+	switch valueType.Kind() {
+	case reflect.Struct:
+		var object map[string]json.RawMessage
+		if json.Unmarshal(content, &object) != nil {
+			return path
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field, loaded := jsonField(valueType, key)
+			if !loaded {
+				continue
+			}
+			if fieldPath := decodePath(object[key], field.Type, JoinPath(path, key)); fieldPath != "" {
+				return fieldPath
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var elements []json.RawMessage
+		if json.Unmarshal(content, &elements) != nil {
+			return path
+		}
+		for i, element := range elements {
+			if elementPath := decodePath(element, valueType.Elem(), IndexPath(path, i)); elementPath != "" {
+				return elementPath
+			}
+		}
+	case reflect.Map:
+		var object map[string]json.RawMessage
+		if json.Unmarshal(content, &object) != nil {
+			return path
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if elementPath := decodePath(object[key], valueType.Elem(), JoinPath(path, key)); elementPath != "" {
+				return elementPath
+			}
+		}
+	}
+	return path
+}
+
+// jsonField returns the field encoding/json decodes key into, preferring an exact match of the name.
+func jsonField(structType reflect.Type, key string) (reflect.StructField, bool) {
+	var foldedField reflect.StructField
+	var folded bool
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Pointer {
+				embeddedType = embeddedType.Elem()
+			}
+			if embeddedType.Kind() == reflect.Struct {
+				if embeddedField, loaded := jsonField(embeddedType, key); loaded {
+					return embeddedField, true
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if name == key {
+			return field, true
+		}
+		if !folded && strings.EqualFold(name, key) {
+			foldedField, folded = field, true
+		}
+	}
+	return foldedField, folded
+}
+
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		if index := strings.LastIndexByte(path, '['); index > 0 {
+			return path[:index]
+		}
+	}
+	if index := strings.LastIndexByte(path, '.'); index > 0 {
+		return path[:index]
+	}
+	return ""
+}
+
+// ConvertFormat converts a YAML or TOML configuration into JSON, so it goes through the same migration path.
+// The returned LineMap is nil for JSON content.
+func ConvertFormat(format string, content []byte) ([]byte, LineMap, error) {
+	switch format {
+	case FormatJSON, FormatAuto, "":
+		return content, nil, nil
+	case FormatYAML:
+		var document yaml.Node
+		err := yaml.Unmarshal(content, &document)
+		if err != nil {
+			return nil, nil, E.Cause(err, "parse YAML")
+		}
+		lines := make(LineMap)
+		value, err := convertYAMLNode(&document, "", lines)
+		if err != nil {
+			return nil, nil, E.Cause(err, "convert YAML")
+		}
+		if value == nil {
+			value = map[string]any{}
+		}
+		content, err = json.Marshal(value)
+		if err != nil {
+			return nil, nil, E.Cause(err, "convert YAML")
+		}
+		return content, lines, nil
+	case FormatTOML:
+		tree, err := toml.LoadBytes(content)
+		if err != nil {
+			return nil, nil, E.Cause(err, "parse TOML")
+		}
+		lines := make(LineMap)
+		content, err = json.Marshal(convertTOMLTree(tree, "", lines))
+		if err != nil {
+			return nil, nil, E.Cause(err, "convert TOML")
+		}
+		return content, lines, nil
+	default:
+		return nil, nil, E.New("unknown configuration format: ", format)
+	}
+}
+
+func convertYAMLNode(node *yaml.Node, path string, lines LineMap) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return convertYAMLNode(node.Content[0], path, lines)
+	case yaml.AliasNode:
+		return convertYAMLNode(node.Alias, path, lines)
+	case yaml.MappingNode:
+		result := make(map[string]any)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			if keyNode.Tag == "!!merge" {
+				err := mergeYAMLNode(result, valueNode, path, lines)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			keyPath := JoinPath(path, keyNode.Value)
+			lines[keyPath] = keyNode.Line
+			value, err := convertYAMLNode(valueNode, keyPath, lines)
+			if err != nil {
+				return nil, err
+			}
+			result[keyNode.Value] = value
+		}
+		return result, nil
+	case yaml.SequenceNode:
+		result := make([]any, 0, len(node.Content))
+		for i, element := range node.Content {
+			elementPath := IndexPath(path, i)
+			lines[elementPath] = element.Line
+			value, err := convertYAMLNode(element, elementPath, lines)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	default:
+		var value any
+		err := node.Decode(&value)
+		if err != nil {
+			return nil, E.Cause(err, "line ", node.Line)
+		}
+		return value, nil
+	}
+}
+
+// mergeYAMLNode applies a YAML merge key (<<), keys already present take precedence.
+func mergeYAMLNode(result map[string]any, node *yaml.Node, path string, lines LineMap) error {
+	var sources []*yaml.Node
+	if node.Kind == yaml.SequenceNode {
+		sources = node.Content
+	} else {
+		sources = []*yaml.Node{node}
+	}
+	for _, source := range sources {
+		value, err := convertYAMLNode(source, path, lines)
+		if err != nil {
+			return err
+		}
+		mapping, isMapping := value.(map[string]any)
+		if !isMapping {
+			return E.New("line ", source.Line, ": merge value is not a mapping")
+		}
+		for key, element := range mapping {
+			if _, loaded := result[key]; !loaded {
+				result[key] = element
+			}
+		}
+	}
+	return nil
+}
+
+func convertTOMLTree(tree *toml.Tree, path string, lines LineMap) map[string]any {
+	result := make(map[string]any)
+	for _, key := range tree.Keys() {
+		keyPath := JoinPath(path, key)
+		if position := tree.GetPositionPath([]string{key}); position.Line > 0 {
+			lines[keyPath] = position.Line
+		}
+		result[key] = convertTOMLValue(tree.GetPath([]string{key}), keyPath, lines)
+	}
+	return result
+}
+
+func convertTOMLValue(value any, path string, lines LineMap) any {
+	switch valueType := value.(type) {
+	case *toml.Tree:
+		return convertTOMLTree(valueType, path, lines)
+	case []*toml.Tree:
+		result := make([]any, 0, len(valueType))
+		for i, element := range valueType {
+			elementPath := IndexPath(path, i)
+			if position := element.Position(); position.Line > 0 {
+				lines[elementPath] = position.Line
+			}
+			result = append(result, convertTOMLTree(element, elementPath, lines))
+		}
+		return result
+	case []any:
+		result := make([]any, 0, len(valueType))
+		for i, element := range valueType {
+			result = append(result, convertTOMLValue(element, IndexPath(path, i), lines))
+		}
+		return result
+	default:
+		return value
+	}
+}
@@ -0,0 +1,180 @@
+package v2box
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	F "github.com/sagernet/sing/common/format"
+)
+
+func TestConvertFormat(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		format   string
+		content  string
+		expected string
+		lines    LineMap
+	}{
+		{
+			name:     "json",
+			format:   FormatJSON,
+			content:  `{"log": {}}`,
+			expected: `{"log": {}}`,
+		},
+		{
+			name:   "yaml",
+			format: FormatYAML,
+			content: `log:
+  loglevel: debug
+inbounds:
+  - port: 1080
+    protocol: socks
+base: &base
+  protocol: freedom
+outbounds:
+  - <<: *base
+    tag: direct
+`,
+			expected: `{"base":{"protocol":"freedom"},"inbounds":[{"port":1080,"protocol":"socks"}],"log":{"loglevel":"debug"},"outbounds":[{"protocol":"freedom","tag":"direct"}]}`,
+			lines: LineMap{
+				"log":                   1,
+				"log.loglevel":          2,
+				"inbounds":              3,
+				"inbounds[0]":           4,
+				"inbounds[0].port":      4,
+				"inbounds[0].protocol":  5,
+				"base":                  6,
+				"base.protocol":         7,
+				"outbounds":             8,
+				"outbounds[0]":          9,
+				"outbounds[0].protocol": 7,
+				"outbounds[0].tag":      10,
+			},
+		},
+		{
+			name:   "toml",
+			format: FormatTOML,
+			content: `[log]
+loglevel = "debug"
+
+[[inbounds]]
+port = 1080
+protocol = "socks"
+`,
+			expected: `{"inbounds":[{"port":1080,"protocol":"socks"}],"log":{"loglevel":"debug"}}`,
+			lines: LineMap{
+				"log":                  1,
+				"log.loglevel":         2,
+				"inbounds":             4,
+				"inbounds[0]":          4,
+				"inbounds[0].port":     5,
+				"inbounds[0].protocol": 6,
+			},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			content, lines, err := ConvertFormat(testCase.format, []byte(testCase.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != testCase.expected {
+				t.Errorf("expected %s, got %s", testCase.expected, content)
+			}
+			if !reflect.DeepEqual(lines, testCase.lines) {
+				t.Errorf("expected lines %v, got %v", testCase.lines, lines)
+			}
+		})
+	}
+	if _, _, err := ConvertFormat("xml", nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+type testAddress string
+
+func (a *testAddress) UnmarshalJSON(content []byte) error {
+	var address string
+	err := json.Unmarshal(content, &address)
+	if err != nil {
+		return errors.New("invalid address: " + string(content))
+	}
+	*a = testAddress(address)
+	return nil
+}
+
+type testConfig struct {
+	Inbounds []struct {
+		Listen testAddress `json:"listen"`
+		Port   uint16      `json:"port"`
+	} `json:"inbounds"`
+	DNS *struct {
+		Hosts map[string]testAddress `json:"hosts"`
+	} `json:"dns"`
+}
+
+func TestLocateError(t *testing.T) {
+	for _, testCase := range []struct {
+		name    string
+		format  string
+		content string
+		line    int
+		path    string
+	}{
+		{
+			name:    "yaml type",
+			format:  FormatYAML,
+			content: "inbounds:\n  - listen: 127.0.0.1\n  - port: a\n",
+			line:    3,
+		},
+		{
+			name:    "yaml unmarshaler",
+			format:  FormatYAML,
+			content: "inbounds:\n  - listen: 127.0.0.1\n  - port: 1080\n    listen: [1, 2]\n",
+			line:    4,
+			path:    "inbounds[1].listen",
+		},
+		{
+			name:    "yaml unmarshaler in map",
+			format:  FormatYAML,
+			content: "dns:\n  hosts:\n    a.com: 1.1.1.1\n    b.com: [1, 2]\n",
+			line:    4,
+			path:    "dns.hosts.b.com",
+		},
+		{
+			name:    "toml type",
+			format:  FormatTOML,
+			content: "[[inbounds]]\nlisten = \"127.0.0.1\"\n\n[[inbounds]]\nport = -1\n",
+			line:    5,
+		},
+		{
+			name:    "toml unmarshaler",
+			format:  FormatTOML,
+			content: "[[inbounds]]\nport = 1080\nlisten = 1\n",
+			line:    3,
+			path:    "inbounds[0].listen",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			content, lines, err := ConvertFormat(testCase.format, []byte(testCase.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var config testConfig
+			err = DecodeJSON(content, &config)
+			if err == nil {
+				t.Fatal("expected a decode error")
+			}
+			var pathErr *PathError
+			if testCase.path != "" && (!errors.As(err, &pathErr) || pathErr.Path != testCase.path) {
+				t.Errorf("expected path %s, got %v", testCase.path, err)
+			}
+			err = lines.LocateError(content, err)
+			if expected := F.ToString("line ", testCase.line, ": "); !strings.HasPrefix(err.Error(), expected) {
+				t.Errorf("expected error at line %d, got %v", testCase.line, err)
+			}
+		})
+	}
+}
@@ -3,13 +3,17 @@ module github.com/sagernet/v2box
 go 1.20
 
 require (
+	github.com/golang/protobuf v1.5.3
 	github.com/maxmind/mmdbwriter v0.0.0-20230315153935-be21eaf06f90
+	github.com/pelletier/go-toml v1.9.5
 	github.com/sagernet/sing v0.2.1-0.20230318094614-4bbf5f2c3046
 	github.com/sagernet/sing-box v1.1.6-0.20230319124622-e717852c73a9
 	github.com/sagernet/sing-dns v0.1.4
 	github.com/spf13/cobra v1.6.1
 	github.com/v2fly/v2ray-core/v5 v5.4.0
 	github.com/xtls/xray-core v1.8.1-0.20230320070138-172f353bd7fa
+	google.golang.org/protobuf v1.30.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -32,7 +36,6 @@ require (
 	github.com/go-task/slim-sprig v0.0.0-20210107165309-348f09dbbbc0 // indirect
 	github.com/gofrs/uuid v4.4.0+incompatible // indirect
 	github.com/golang/mock v1.6.0 // indirect
-	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/google/btree v1.1.2 // indirect
 	github.com/google/pprof v0.0.0-20230228050547-1710fef4ab10 // indirect
 	github.com/gorilla/websocket v1.5.0 // indirect
@@ -51,7 +54,6 @@ require (
 	github.com/onsi/ginkgo/v2 v2.9.0 // indirect
 	github.com/ooni/go-libtor v1.1.7 // indirect
 	github.com/oschwald/maxminddb-golang v1.10.0 // indirect
-	github.com/pelletier/go-toml v1.9.5 // indirect
 	github.com/pierrec/lz4/v4 v4.1.14 // indirect
 	github.com/pires/go-proxyproto v0.7.0 // indirect
 	github.com/quic-go/qpack v0.4.0 // indirect
@@ -100,8 +102,6 @@ require (
 	golang.org/x/tools v0.7.0 // indirect
 	google.golang.org/genproto v0.0.0-20230306155012-7f2fa6fef1f4 // indirect
 	google.golang.org/grpc v1.53.0 // indirect
-	google.golang.org/protobuf v1.30.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	gvisor.dev/gvisor v0.0.0-20220901235040-6ca97ef2ce1c // indirect
 	lukechampine.com/blake3 v1.1.7 // indirect
 )
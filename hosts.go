@@ -0,0 +1,192 @@
+package v2box
+
+import (
+	"bytes"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/json"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// ParseHosts decodes the static hosts of the DNS config, as neither core exports the addresses of its host mappings.
+func ParseHosts(content []byte) (map[string]option.Listable[string], error) {
+	var config struct {
+		DNS *struct {
+			Hosts map[string]option.Listable[string] `json:"hosts"`
+		} `json:"dns"`
+	}
+	decoder := json.NewDecoder(json.NewCommentFilter(bytes.NewReader(content)))
+	err := decoder.Decode(&config)
+	if err != nil {
+		return nil, err
+	}
+	if config.DNS == nil {
+		return nil, nil
+	}
+	return config.DNS.Hosts, nil
+}
+
+// HostRoute sends connections of the domains of static hosts to their address.
+type HostRoute struct {
+	Address netip.Addr
+	Paths   []string
+	Rule    option.DefaultRule
+}
+
+// MigrateHosts converts static hosts, sing-box DNS has no static answers:
+// hosts to unspecified addresses are answered with an empty response by the block server,
+// hosts to other addresses are returned as routes for RouteHosts, as they depend on the migrated routing.
+// parseDomain is the domain matcher parser of the configuration type.
+func MigrateHosts(hosts map[string]option.Listable[string], parseDomain func(domain string, rule *option.DefaultRule) error, dnsOptions *option.DNSOptions, report *Report) []HostRoute {
+	domains := make([]string, 0, len(hosts))
+	for domain := range hosts {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	var blockRule option.DefaultRule
+	var hostRoutes []HostRoute
+	routeIndex := make(map[netip.Addr]int)
+	for _, domain := range domains {
+		path := JoinPath("dns.hosts", domain)
+		addresses, err := parseHostAddresses(hosts[domain])
+		if err != nil {
+			report.Drop(path, "", err)
+			continue
+		}
+		var domainRule option.DefaultRule
+		if strings.Contains(domain, ":") {
+			err = parseDomain(domain, &domainRule)
+		} else {
+			err = parseDomain("full:"+domain, &domainRule)
+		}
+		if err != nil {
+			report.Drop(path, "", err)
+			continue
+		}
+		if common.All(addresses, netip.Addr.IsUnspecified) {
+			appendDomains(&blockRule, domainRule)
+			continue
+		}
+		if len(addresses) > 1 {
+			report.Warn(path, "", "only the first of ", len(addresses), " addresses is migrated")
+		}
+		index, loaded := routeIndex[addresses[0]]
+		if !loaded {
+			index = len(hostRoutes)
+			routeIndex[addresses[0]] = index
+			hostRoutes = append(hostRoutes, HostRoute{Address: addresses[0]})
+		}
+		hostRoutes[index].Paths = append(hostRoutes[index].Paths, path)
+		appendDomains(&hostRoutes[index].Rule, domainRule)
+	}
+	if dnsRule := DomainDNSRule(blockRule, "block"); dnsRule.IsValid() {
+		dnsOptions.Servers = append(dnsOptions.Servers, option.DNSServerOptions{
+			Tag:     "block",
+			Address: "rcode://success",
+		})
+		dnsOptions.Rules = append(dnsOptions.Rules, option.DNSRule{
+			Type:           C.RuleTypeDefault,
+			DefaultOptions: dnsRule,
+		})
+	}
+	return hostRoutes
+}
+
+// RouteHosts appends rules sending the domains of host routes to their address through direct outbounds.
+// V2Ray only applies hosts to connections it resolves itself, proxies receive the domain,
+// so the rules follow the migrated rules and are only added if unmatched connections go out directly.
+func RouteHosts(hostRoutes []HostRoute, options *option.Options, report *Report) {
+	if len(hostRoutes) == 0 {
+		return
+	}
+	if !finalOutboundDirect(options) {
+		for _, hostRoute := range hostRoutes {
+			for _, path := range hostRoute.Paths {
+				report.Drop(path, "", E.New("connections not matching the routing rules are not sent out directly, hosts can not be applied"))
+			}
+		}
+		return
+	}
+	outboundTags := NewTagSet(common.Map(options.Outbounds, func(it option.Outbound) string {
+		return it.Tag
+	})...)
+	if options.Route == nil {
+		options.Route = &option.RouteOptions{}
+	}
+	for _, hostRoute := range hostRoutes {
+		tag := outboundTags.New("hosts-" + hostRoute.Address.String())
+		options.Outbounds = append(options.Outbounds, option.Outbound{
+			Type: C.TypeDirect,
+			Tag:  tag,
+			DirectOptions: option.DirectOutboundOptions{
+				OverrideAddress: hostRoute.Address.String(),
+			},
+		})
+		hostRule := hostRoute.Rule
+		hostRule.Outbound = tag
+		options.Route.Rules = append(options.Route.Rules, option.Rule{
+			Type:           C.RuleTypeDefault,
+			DefaultOptions: hostRule,
+		})
+		for _, path := range hostRoute.Paths {
+			report.Warn(path, "", "DNS queries are not answered by hosts, connections not matching the routing rules are sent to ", hostRoute.Address, " directly")
+		}
+	}
+}
+
+// finalOutboundDirect returns whether connections not matching any rule use a direct outbound without overrides.
+func finalOutboundDirect(options *option.Options) bool {
+	var finalTag string
+	if options.Route != nil {
+		finalTag = options.Route.Final
+	}
+	for _, outbound := range options.Outbounds {
+		if finalTag != "" && outbound.Tag != finalTag {
+			continue
+		}
+		directOptions := outbound.DirectOptions
+		return outbound.Type == C.TypeDirect && directOptions.OverrideAddress == "" && directOptions.OverridePort == 0 && directOptions.Detour == ""
+	}
+	return false
+}
+
+func parseHostAddresses(values []string) ([]netip.Addr, error) {
+	if len(values) == 0 {
+		return nil, E.New("missing host address")
+	}
+	addresses := make([]netip.Addr, 0, len(values))
+	for _, value := range values {
+		address := M.ParseAddr(value)
+		if !address.IsValid() {
+			return nil, E.New("host mapping to domain ", value, " is not supported")
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+func appendDomains(rule *option.DefaultRule, domainRule option.DefaultRule) {
+	rule.Domain = append(rule.Domain, domainRule.Domain...)
+	rule.DomainSuffix = append(rule.DomainSuffix, domainRule.DomainSuffix...)
+	rule.DomainKeyword = append(rule.DomainKeyword, domainRule.DomainKeyword...)
+	rule.DomainRegex = append(rule.DomainRegex, domainRule.DomainRegex...)
+	rule.Geosite = append(rule.Geosite, domainRule.Geosite...)
+}
+
+// DomainDNSRule returns a DNS rule sending queries for the domains of rule to server.
+func DomainDNSRule(rule option.DefaultRule, server string) option.DefaultDNSRule {
+	return option.DefaultDNSRule{
+		Domain:        rule.Domain,
+		DomainSuffix:  rule.DomainSuffix,
+		DomainKeyword: rule.DomainKeyword,
+		DomainRegex:   rule.DomainRegex,
+		Geosite:       rule.Geosite,
+		Server:        server,
+	}
+}
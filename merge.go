@@ -0,0 +1,59 @@
+package v2box
+
+import (
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// ConfigFile is one of several configuration files to be merged.
+// Name is used by merge rules depending on the file name, e.g. Xray's "tail" outbounds.
+type ConfigFile struct {
+	Name    string
+	Content []byte
+}
+
+// Merger merges configuration files in order with the merge rules of the registered type.
+type Merger func(files []ConfigFile) ([]byte, error)
+
+var mergerMap map[string]Merger
+
+func RegisterMerger(typeName string, merger Merger) {
+	if mergerMap == nil {
+		mergerMap = make(map[string]Merger)
+	}
+	mergerMap[typeName] = merger
+}
+
+// Merge merges configuration files into one configuration.
+// In auto mode the type is detected by summing the detection scores of all files.
+func Merge(typeName string, files []ConfigFile) ([]byte, error) {
+	if len(files) == 0 {
+		return nil, E.New("missing configuration files")
+	}
+	if len(files) == 1 {
+		return files[0].Content, nil
+	}
+	if typeName == "auto" {
+		typeName = detectFiles(files)
+	}
+	merger, loaded := mergerMap[typeName]
+	if !loaded {
+		return nil, E.New("merging multiple files is not supported by configuration type: ", typeName)
+	}
+	return merger(files)
+}
+
+func detectFiles(files []ConfigFile) string {
+	scores := make(map[string]int)
+	for _, file := range files {
+		for _, detection := range Detect(file.Content) {
+			scores[detection.TypeName] += detection.Score
+		}
+	}
+	var typeName string
+	for name, score := range scores {
+		if typeName == "" || score > scores[typeName] || score == scores[typeName] && name < typeName {
+			typeName = name
+		}
+	}
+	return typeName
+}
@@ -0,0 +1,92 @@
+package v2box
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/logger"
+)
+
+func registerMergeTest(typeName string, keyword string) {
+	Register(typeName, "", func(configuration []byte, options MigrateOptions, logger logger.Logger) (option.Options, Report, error) {
+		return option.Options{}, Report{}, nil
+	})
+	RegisterDetector(typeName, func(configuration []byte) Detection {
+		var detection Detection
+		if bytes.Contains(configuration, []byte(keyword)) {
+			detection.Add(1, keyword)
+		}
+		return detection
+	})
+	RegisterMerger(typeName, func(files []ConfigFile) ([]byte, error) {
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			names = append(names, file.Name)
+		}
+		return []byte(typeName + ":" + strings.Join(names, ",")), nil
+	})
+}
+
+func TestMerge(t *testing.T) {
+	registerMergeTest("merge-a", "alpha")
+	registerMergeTest("merge-b", "beta")
+	defer func() {
+		for _, typeName := range []string{"merge-a", "merge-b"} {
+			delete(migrationMap, typeName)
+			delete(versionMap, typeName)
+			delete(detectorMap, typeName)
+			delete(mergerMap, typeName)
+		}
+	}()
+	for _, testCase := range []struct {
+		name     string
+		typeName string
+		files    []ConfigFile
+		expected string
+	}{
+		{
+			name:     "single file",
+			typeName: "merge-a",
+			files:    []ConfigFile{{Name: "1.json", Content: []byte("content")}},
+			expected: "content",
+		},
+		{
+			name:     "file order",
+			typeName: "merge-b",
+			files:    []ConfigFile{{Name: "2.json"}, {Name: "1.json"}},
+			expected: "merge-b:2.json,1.json",
+		},
+		{
+			// scores of all files are summed.
+			name:     "auto",
+			typeName: "auto",
+			files:    []ConfigFile{{Name: "1.json", Content: []byte("alpha")}, {Name: "2.json", Content: []byte("beta")}, {Name: "3.json", Content: []byte("beta")}},
+			expected: "merge-b:1.json,2.json,3.json",
+		},
+		{
+			// equal scores are broken by type name.
+			name:     "auto tie",
+			typeName: "auto",
+			files:    []ConfigFile{{Name: "1.json", Content: []byte("alpha beta")}, {Name: "2.json"}},
+			expected: "merge-a:1.json,2.json",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			content, err := Merge(testCase.typeName, testCase.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != testCase.expected {
+				t.Errorf("expected %s, got %s", testCase.expected, content)
+			}
+		})
+	}
+	if _, err := Merge("merge-c", []ConfigFile{{}, {}}); err == nil {
+		t.Error("expected an error for a type without merger")
+	}
+	if _, err := Merge("merge-a", nil); err == nil {
+		t.Error("expected an error without files")
+	}
+}
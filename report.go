@@ -0,0 +1,184 @@
+package v2box
+
+import (
+	"io"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/json"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/logger"
+)
+
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+func (s Severity) String() string {
+	return string(s)
+}
+
+// ReportEntry describes a configuration element that was dropped or only partially migrated.
+type ReportEntry struct {
+	Path     string   `json:"path"`
+	Line     int      `json:"line,omitempty"`
+	Tag      string   `json:"tag,omitempty"`
+	Severity Severity `json:"severity"`
+	Reason   string   `json:"reason"`
+}
+
+func (e ReportEntry) String() string {
+	var builder strings.Builder
+	builder.WriteString(e.Path)
+	if e.Tag != "" {
+		builder.WriteString(" (")
+		builder.WriteString(e.Tag)
+		builder.WriteString(")")
+	}
+	if e.Line > 0 {
+		builder.WriteString(" at line ")
+		builder.WriteString(F.ToString(e.Line))
+	}
+	builder.WriteString(": ")
+	builder.WriteString(e.Reason)
+	return builder.String()
+}
+
+type Report struct {
+	Entries []ReportEntry `json:"entries"`
+}
+
+func (r *Report) Add(severity Severity, path string, tag string, reason ...any) {
+	r.Entries = append(r.Entries, ReportEntry{
+		Path:     path,
+		Tag:      tag,
+		Severity: severity,
+		Reason:   F.ToString(reason...),
+	})
+}
+
+func (r *Report) Info(path string, tag string, reason ...any) {
+	r.Add(SeverityInfo, path, tag, reason...)
+}
+
+func (r *Report) Warn(path string, tag string, reason ...any) {
+	r.Add(SeverityWarning, path, tag, reason...)
+}
+
+// Drop records an element that was not migrated at all.
+// The path of a *PathError returned by nested migration code is appended to path.
+func (r *Report) Drop(path string, tag string, err error) {
+	for {
+		pathErr, isPathErr := err.(*PathError)
+		if !isPathErr {
+			break
+		}
+		path = JoinPath(path, pathErr.Path)
+		err = pathErr.Cause
+	}
+	r.Add(SeverityError, path, tag, err)
+}
+
+func (r *Report) Filter(severity Severity) []ReportEntry {
+	var entries []ReportEntry
+	for _, entry := range r.Entries {
+		if entry.Severity == severity {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Locate sets the original line of every entry, for configurations converted from other formats.
+func (r *Report) Locate(lines LineMap) {
+	for i := range r.Entries {
+		r.Entries[i].Line = lines.Line(r.Entries[i].Path)
+	}
+}
+
+// Err returns an error listing every dropped element, or nil if nothing was dropped.
+func (r *Report) Err() error {
+	dropped := r.Filter(SeverityError)
+	if len(dropped) == 0 {
+		return nil
+	}
+	var builder strings.Builder
+	builder.WriteString(F.ToString(len(dropped), " configuration elements are not migrated:"))
+	for _, entry := range dropped {
+		builder.WriteString("\n  ")
+		builder.WriteString(entry.String())
+	}
+	return E.New(builder.String())
+}
+
+func (r *Report) Log(logger logger.Logger) {
+	for _, entry := range r.Entries {
+		switch entry.Severity {
+		case SeverityInfo:
+			logger.Info(entry)
+		case SeverityWarning:
+			logger.Warn(entry)
+		default:
+			logger.Warn("ignoring ", entry)
+		}
+	}
+}
+
+func (r *Report) WriteText(writer io.Writer) error {
+	for _, entry := range r.Entries {
+		_, err := io.WriteString(writer, F.ToString("[", entry.Severity, "] ", entry, "\n"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Report) WriteJSON(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// PathError is an error attached to a JSON path relative to the element being migrated.
+type PathError struct {
+	Path  string
+	Cause error
+}
+
+func NewPathError(path string, message ...any) error {
+	return &PathError{path, E.New(message...)}
+}
+
+func WrapPathError(path string, cause error) error {
+	return &PathError{path, cause}
+}
+
+func (e *PathError) Error() string {
+	return e.Path + ": " + e.Cause.Error()
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Cause
+}
+
+func JoinPath(path string, element string) string {
+	if path == "" {
+		return element
+	}
+	if element == "" {
+		return path
+	}
+	if strings.HasPrefix(element, "[") {
+		return path + element
+	}
+	return path + "." + element
+}
+
+func IndexPath(path string, index int) string {
+	return F.ToString(path, "[", index, "]")
+}
@@ -0,0 +1,37 @@
+package v2box
+
+import (
+	F "github.com/sagernet/sing/common/format"
+)
+
+// TagSet tracks the tags in use, so elements generated by a migration do not take the tag of another element.
+type TagSet map[string]bool
+
+func NewTagSet(tags ...string) TagSet {
+	tagSet := make(TagSet)
+	for _, tag := range tags {
+		if tag != "" {
+			tagSet[tag] = true
+		}
+	}
+	return tagSet
+}
+
+// New returns tag, or tag with the first free suffix from -2 if tag is in use, and marks the returned tag in use.
+func (s TagSet) New(tag string) string {
+	newTag := tag
+	for i := 2; s[newTag]; i++ {
+		newTag = F.ToString(tag, "-", i)
+	}
+	s[newTag] = true
+	return newTag
+}
+
+// Add marks tag in use, it returns false if tag is already in use.
+func (s TagSet) Add(tag string) bool {
+	if s[tag] {
+		return false
+	}
+	s[tag] = true
+	return true
+}
@@ -0,0 +1,130 @@
+package v2rayjson
+
+import (
+	"encoding/json"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/v2box"
+
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/duration"
+	v4json "github.com/v2fly/v2ray-core/v5/infra/conf/v4"
+)
+
+// migrateBalancer converts a routing balancer into a group outbound of the migrated outbounds matching its selectors.
+// Balancers probing their outbounds become urltest with the probe options of urlTestOptions, random balancers become selector.
+func migrateBalancer(tag string, selectors []string, strategy string, fallbackTag string, urlTestOptions option.URLTestOutboundOptions, outbounds []option.Outbound, outboundTags v2box.TagSet, path string, report *v2box.Report) (option.Outbound, error) {
+	if !outboundTags.Add(tag) {
+		return option.Outbound{}, v2box.NewPathError("tag", "tag ", tag, " is already used by an outbound or balancer")
+	}
+	selectedTags, err := selectOutbounds(selectors, outbounds)
+	if err != nil {
+		return option.Outbound{}, v2box.WrapPathError("selector", err)
+	}
+	if fallbackTag != "" {
+		report.Warn(path+".fallbackTag", tag, "fallback tag is not migrated")
+	}
+	switch strings.ToLower(strategy) {
+	case "leastping", "leastload":
+		urlTestOptions.Outbounds = selectedTags
+		return option.Outbound{
+			Type:           C.TypeURLTest,
+			Tag:            tag,
+			URLTestOptions: urlTestOptions,
+		}, nil
+	case "", "random":
+		report.Info(path+".strategy", tag, "random balancer is migrated to selector with ", selectedTags[0], " selected")
+		return option.Outbound{
+			Type: C.TypeSelector,
+			Tag:  tag,
+			SelectorOptions: option.SelectorOutboundOptions{
+				Outbounds: selectedTags,
+			},
+		}, nil
+	default:
+		return option.Outbound{}, v2box.NewPathError("strategy", "unknown balancing strategy: ", strategy)
+	}
+}
+
+// migrateObservatory converts an observatory not used by any balancer into a urltest outbound,
+// so the outbounds it observes are still probed. The outbound takes the first free tag from tag.
+func migrateObservatory(tag string, selectors []string, urlTestOptions option.URLTestOutboundOptions, outbounds []option.Outbound, outboundTags v2box.TagSet) (option.Outbound, error) {
+	selectedTags, err := selectOutbounds(selectors, outbounds)
+	if err != nil {
+		return option.Outbound{}, v2box.WrapPathError("subjectSelector", err)
+	}
+	urlTestOptions.Outbounds = selectedTags
+	return option.Outbound{
+		Type:           C.TypeURLTest,
+		Tag:            outboundTags.New(tag),
+		URLTestOptions: urlTestOptions,
+	}, nil
+}
+
+// selectOutbounds returns the tags of outbounds matching any of the tag prefixes in selectors.
+func selectOutbounds(selectors []string, outbounds []option.Outbound) ([]string, error) {
+	var outboundTags []string
+	for _, outbound := range outbounds {
+		// groups among migrated outbounds only come from multiple endpoints, which are matched instead
+		if outbound.Tag == "" || outbound.Type == C.TypeURLTest || outbound.Type == C.TypeSelector {
+			continue
+		}
+		if common.Any(selectors, func(it string) bool {
+			return strings.HasPrefix(outbound.Tag, it)
+		}) {
+			outboundTags = append(outboundTags, outbound.Tag)
+		}
+	}
+	if len(outboundTags) == 0 {
+		return nil, E.New("no migrated outbound matches selector ", strings.Join(selectors, ", "))
+	}
+	return outboundTags, nil
+}
+
+func parseObservatory(observatoryConfig *v4json.ObservatoryConfig) option.URLTestOutboundOptions {
+	return option.URLTestOutboundOptions{
+		URL:      observatoryConfig.ProbeURL,
+		Interval: option.Duration(observatoryConfig.ProbeInterval),
+	}
+}
+
+func parseBurstObservatory(observatoryConfig *v4json.BurstObservatoryConfig) option.URLTestOutboundOptions {
+	if observatoryConfig.HealthCheck == nil {
+		return option.URLTestOutboundOptions{}
+	}
+	return option.URLTestOutboundOptions{
+		URL:      observatoryConfig.HealthCheck.Destination,
+		Interval: option.Duration(observatoryConfig.HealthCheck.Interval),
+	}
+}
+
+type leastLoadSettings struct {
+	Costs     json.RawMessage     `json:"costs"`
+	Baselines []duration.Duration `json:"baselines"`
+	Expected  int32               `json:"expected"`
+	MaxRTT    duration.Duration   `json:"maxRTT"`
+	Tolerance float64             `json:"tolerance"`
+}
+
+// leastLoadNotMigrated explains dropping leastLoad settings, the RTT baselines group outbounds into tiers
+// and differ from the urltest tolerance, which only keeps the selected outbound while within it.
+const leastLoadNotMigrated = "costs, baselines, expected, maxRTT and tolerance are not migrated, urltest selects the outbound with the lowest delay"
+
+// reportStrategySettings reports the settings of a leastLoad balancer, which urltest has no equivalent for.
+func reportStrategySettings(settings *json.RawMessage, path string, tag string, report *v2box.Report) error {
+	if settings == nil {
+		return nil
+	}
+	var leastLoad leastLoadSettings
+	err := json.Unmarshal(*settings, &leastLoad)
+	if err != nil {
+		return v2box.WrapPathError("settings", err)
+	}
+	if len(leastLoad.Costs) > 0 || len(leastLoad.Baselines) > 0 || leastLoad.Expected != 0 || leastLoad.MaxRTT != 0 || leastLoad.Tolerance != 0 {
+		report.Warn(path+".settings", tag, leastLoadNotMigrated)
+	}
+	return nil
+}
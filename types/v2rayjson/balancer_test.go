@@ -0,0 +1,41 @@
+package v2rayjson
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sagernet/v2box"
+)
+
+func TestMigrateBalancerTags(t *testing.T) {
+	options, report := migrateTest(t, `{
+		"outbounds": [
+			{"protocol": "blackhole", "tag": "proxy-a"},
+			{"protocol": "blackhole", "tag": "proxy-b"}
+		],
+		"routing": {"balancers": [
+			{"tag": "proxy-a", "selector": ["proxy"]},
+			{"tag": "balancer", "selector": ["proxy"]},
+			{"tag": "balancer", "selector": ["proxy"]}
+		]}
+	}`)
+	if expected := []string{"proxy-a", "proxy-b", "balancer"}; !reflect.DeepEqual(outboundTags(options), expected) {
+		t.Errorf("expected outbounds %v, got %v", expected, outboundTags(options))
+	}
+	for _, path := range []string{"routing.balancers[0].tag", "routing.balancers[2].tag"} {
+		if !hasReport(report, v2box.SeverityError, path) {
+			t.Errorf("expected %s to be dropped", path)
+		}
+	}
+}
+
+func TestMigrateObservatoryTag(t *testing.T) {
+	options, _ := migrateTest(t, `{
+		"outbounds": [{"protocol": "blackhole", "tag": "proxy-a"}],
+		"routing": {"balancers": [{"tag": "observatory", "selector": ["proxy"]}]},
+		"observatory": {"subjectSelector": ["proxy"]}
+	}`)
+	if expected := []string{"proxy-a", "observatory", "observatory-2"}; !reflect.DeepEqual(outboundTags(options), expected) {
+		t.Errorf("expected outbounds %v, got %v", expected, outboundTags(options))
+	}
+}
@@ -4,23 +4,14 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
-	E "github.com/sagernet/sing/common/exceptions"
-	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/v2box"
 
 	v2ray_net "github.com/v2fly/v2ray-core/v5/common/net"
-	"github.com/v2fly/v2ray-core/v5/common/protocol"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	v4json "github.com/v2fly/v2ray-core/v5/infra/conf/v4"
 )
 
-func parseServerAddress(servers []*protocol.ServerEndpoint) (M.Socksaddr, []*protocol.User) {
-	if len(servers) == 0 {
-		return M.Socksaddr{}, nil
-	}
-	return M.ParseSocksaddrHostPort(servers[0].Address.AsAddress().String(), uint16(servers[0].Port)), servers[0].User
-}
-
 func parseNetworkList(networks *cfgcommon.NetworkList) string {
 	if networks == nil {
 		return ""
@@ -68,7 +59,7 @@ func parseTransport(streamSettings *v4json.StreamConfig) (option.V2RayTransportO
 	case "tcp":
 		if tcpSettings := streamSettings.TCPSettings; tcpSettings != nil {
 			if tcpSettings.HeaderConfig != nil {
-				return option.V2RayTransportOptions{}, E.New("unsupported v2ray TCP transport with header")
+				return option.V2RayTransportOptions{}, v2box.NewPathError("streamSettings.tcpSettings.header", "unsupported v2ray TCP transport with header")
 			}
 		}
 	case "http":
@@ -89,7 +80,7 @@ func parseTransport(streamSettings *v4json.StreamConfig) (option.V2RayTransportO
 				}
 			}
 		}
-	case "ws":
+	case "websocket":
 		transportOptions.Type = C.V2RayTransportTypeWebsocket
 		if wsSettings := streamSettings.WSSettings; wsSettings != nil {
 			transportOptions.WebsocketOptions.Path = wsSettings.Path
@@ -112,7 +103,7 @@ func parseTransport(streamSettings *v4json.StreamConfig) (option.V2RayTransportO
 	case "quic":
 		transportOptions.Type = C.V2RayTransportTypeQUIC
 	default:
-		return option.V2RayTransportOptions{}, E.New("unsupported v2ray transport type: ", networkName)
+		return option.V2RayTransportOptions{}, v2box.NewPathError("streamSettings.network", "unsupported v2ray transport type: ", networkName)
 	}
 	return transportOptions, nil
 }
@@ -0,0 +1,63 @@
+package v2rayjson
+
+import (
+	"regexp"
+
+	"github.com/sagernet/v2box"
+)
+
+var outboundDomainStrategyPath = regexp.MustCompile(`^outbounds\[\d+]\.domainStrategy$`)
+
+func Detect(content []byte) v2box.Detection {
+	var detection v2box.Detection
+	err := v2box.WalkJSON(content, func(path string, key string, value any) {
+		switch key {
+		case "gunSettings":
+			detection.Add(2, "gun transport settings at ", path)
+		case "network":
+			if value == "gun" {
+				detection.Add(2, "gun transport at ", path)
+			}
+		case "transportLayer":
+			detection.Add(1, "transport layer proxy at ", path)
+		case "domainStrategy":
+			if outboundDomainStrategyPath.MatchString(path) {
+				detection.Add(1, "outbound domain strategy at ", path)
+			}
+		}
+		switch path {
+		case "burstObservatory", "multiObservatory", "browserForwarder", "services":
+			detection.Add(2, "v2ray-only section ", path)
+		}
+	})
+	if err != nil {
+		detection.Add(-10, "invalid JSON: ", err)
+	}
+	return detection
+}
+
+var streamTransportPath = regexp.MustCompile(`^(in|out)bounds\[\d+]\.streamSettings\.transport$`)
+
+func DetectV5(content []byte) v2box.Detection {
+	var detection v2box.Detection
+	err := v2box.WalkJSON(content, func(path string, key string, value any) {
+		switch key {
+		case "transport":
+			if streamTransportPath.MatchString(path) {
+				detection.Add(2, "v5 transport at ", path)
+			}
+		case "transportSettings", "securitySettings":
+			detection.Add(2, "v5 stream settings at ", path)
+		}
+		switch path {
+		case "router":
+			detection.Add(3, "v5 router section")
+		case "services", "extension":
+			detection.Add(1, "v5 section ", path)
+		}
+	})
+	if err != nil {
+		detection.Add(-10, "invalid JSON: ", err)
+	}
+	return detection
+}
@@ -0,0 +1,74 @@
+package v2rayjson
+
+import (
+	"testing"
+
+	"github.com/sagernet/v2box"
+)
+
+func TestDetect(t *testing.T) {
+	for _, testCase := range []struct {
+		name    string
+		content string
+		v4      int
+		v5      int
+		first   string
+	}{
+		{
+			name: "v4 gun",
+			content: `{
+				"outbounds": [{"protocol": "vmess", "domainStrategy": "UseIP", "streamSettings": {"network": "gun", "gunSettings": {"serviceName": "a"}}}],
+				"burstObservatory": {}
+			}`,
+			v4:    7,
+			v5:    0,
+			first: "v2ray",
+		},
+		{
+			name: "v5 router",
+			content: `{
+				"router": {"rule": []},
+				"outbounds": [{"protocol": "vmess", "streamSettings": {"transport": "grpc", "transportSettings": {}}}]
+			}`,
+			v4:    0,
+			v5:    7,
+			first: "v2ray5",
+		},
+		{
+			// services exists in both formats and scores for both.
+			name:    "ambiguous services",
+			content: `{"services": {}}`,
+			v4:      2,
+			v5:      1,
+			first:   "v2ray",
+		},
+		{
+			// equal scores are sorted by type name.
+			name:    "ambiguous plain",
+			content: `{"outbounds": [{"protocol": "freedom"}]}`,
+			v4:      0,
+			v5:      0,
+			first:   "v2ray",
+		},
+		{
+			name:    "invalid",
+			content: `{"outbounds": [`,
+			v4:      -10,
+			v5:      -10,
+			first:   "v2ray",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if detection := Detect([]byte(testCase.content)); detection.Score != testCase.v4 {
+				t.Errorf("expected v4 score %d, got %s", testCase.v4, detection)
+			}
+			if detection := DetectV5([]byte(testCase.content)); detection.Score != testCase.v5 {
+				t.Errorf("expected v5 score %d, got %s", testCase.v5, detection)
+			}
+			detections := v2box.Detect([]byte(testCase.content))
+			if len(detections) == 0 || detections[0].TypeName != testCase.first {
+				t.Errorf("expected %s first, got %v", testCase.first, detections)
+			}
+		})
+	}
+}
@@ -1,65 +1,291 @@
 package v2rayjson
 
 import (
+	"net"
+	"net/url"
 	"strings"
 
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-dns"
 	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
 
+	v2ray_dns "github.com/v2fly/v2ray-core/v5/app/dns"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	conf_dns "github.com/v2fly/v2ray-core/v5/infra/conf/synthetic/dns"
 )
 
-func migrateDNS(dnsConfig conf_dns.DNSConfig, options *option.Options) {
-	defaultServer := common.Find(dnsConfig.Servers, func(it *conf_dns.NameServerConfig) bool {
-		return len(it.Domains) == 0 && len(it.ExpectIPs) > 0
-	})
-	var defaultServerAddress string
-	if defaultServer != nil {
-		if !strings.Contains(defaultServerAddress, "+local://") {
-			defaultServerAddress = defaultServer.Address.String()
-		}
+// migrateDNS converts name servers into DNS servers, and their domains into DNS rules in server order.
+// V2Ray falls back to servers not skipping fallback for unmatched domains, the first of them becomes the final server.
+// The local DNS server and the direct outbound are reused from the configuration, or injected when referenced.
+// The routes of static hosts are returned to be added after the routing rules by v2box.RouteHosts.
+func migrateDNS(dnsConfig conf_dns.DNSConfig, hosts map[string]option.Listable[string], outboundServerRule option.DefaultDNSRule, options *option.Options, outboundTags v2box.TagSet, migrateOptions v2box.MigrateOptions, report *v2box.Report) []v2box.HostRoute {
+	if dnsConfig.ClientIP != nil {
+		report.Warn("dns.clientIp", dnsConfig.Tag, "EDNS client subnet is not supported")
 	}
-	if defaultServerAddress == "" {
-		defaultServerAddress = "tls://8.8.8.8"
+	if dnsConfig.FakeDNS != nil {
+		reportFakeDNS(dnsConfig.FakeDNS, "dns.fakedns", dnsConfig.Tag, report)
 	}
 
 	var dnsOptions option.DNSOptions
 	dnsOptions.Strategy = parseStrategy(dnsConfig.QueryStrategy)
-	dnsOptions.Servers = []option.DNSServerOptions{
-		{
-			Address: "tls://8.8.8.8",
-			Tag:     "remote",
-		},
-		{
-			Address: "local",
-			Tag:     "local",
-			Detour:  "direct",
-		},
+	dnsOptions.DisableCache = parseCacheStrategy(dnsConfig.CacheStrategy, dnsConfig.DisableCache)
+	if len(dnsConfig.Servers) == 0 && len(hosts) == 0 {
+		// both V2Ray and sing-box resolve with the system resolver without name servers
+		if dnsOptions.DNSClientOptions != (option.DNSClientOptions{}) {
+			options.DNS = &dnsOptions
+		}
+		return nil
+	}
+	fallbackStrategy := parseFallbackStrategy(dnsConfig.FallbackStrategy, dnsConfig.DisableFallback, dnsConfig.DisableFallbackIfMatch)
+	directTag, directLoaded := directOutboundTag(options.Outbounds, outboundTags)
+	localLoaded := common.Any(dnsConfig.Servers, func(it *conf_dns.NameServerConfig) bool {
+		return it.Address != nil && strings.EqualFold(it.Address.String(), "localhost")
+	})
+	hostRoutes := v2box.MigrateHosts(hosts, parseDomain, &dnsOptions, report)
+	// the block server is added by v2box.MigrateHosts
+	serverTags := v2box.NewTagSet("block")
+	var localTag string
+	var migratedServers, fallbackServers, matchedFallbackServers, domainServers []string
+	for i, server := range dnsConfig.Servers {
+		path := v2box.IndexPath("dns.servers", i)
+		tag := server.Tag
+		if tag == "" || !serverTags.Add(tag) {
+			tag = serverTags.New(F.ToString("dns-", i))
+		}
+		if server.FakeDNS.FakeDNSConfig != nil {
+			reportFakeDNS(server.FakeDNS.FakeDNSConfig, path+".fakedns", server.Tag, report)
+			report.Drop(path, server.Tag, errFakeDNSServer)
+			continue
+		}
+		serverOptions, err := migrateDNSServer(server.Address, server.Port, tag, directTag)
+		if err != nil {
+			report.Drop(path, server.Tag, err)
+			continue
+		}
+		if serverOptions.AddressResolver != "" && !localLoaded && migrateOptions.DisableInjection {
+			report.Drop(path, server.Tag, E.New("server domain requires the local DNS server, which is not injected"))
+			continue
+		}
+		if serverOptions.Detour != "" && !directLoaded && migrateOptions.DisableInjection {
+			serverOptions.Detour = ""
+			report.Warn(path, server.Tag, "server is queried through the routing, as the direct outbound is not injected")
+		}
+		if serverOptions.Address == "local" && localTag == "" {
+			localTag = tag
+		}
+		if server.ClientIP != nil {
+			report.Warn(path+".clientIp", server.Tag, "EDNS client subnet is not supported")
+		}
+		if server.QueryStrategy != "" {
+			serverOptions.Strategy = parseStrategy(server.QueryStrategy)
+			if serverOptions.Strategy == option.DomainStrategy(dns.DomainStrategyAsIS) && dnsOptions.Strategy != serverOptions.Strategy {
+				report.Warn(path+".queryStrategy", server.Tag, "query strategy ", server.QueryStrategy, " does not override the global strategy")
+			}
+		}
+		if server.CacheStrategy != "" && parseCacheStrategy(server.CacheStrategy, false) != dnsOptions.DisableCache {
+			report.Warn(path+".cacheStrategy", server.Tag, "cache strategy of a single server is not supported")
+		}
+		migratedServers = append(migratedServers, tag)
+		dnsOptions.Servers = append(dnsOptions.Servers, serverOptions)
+		if len(server.ExpectIPs) > 0 {
+			report.Warn(path+".expectIps", server.Tag, "expected IPs are not migrated")
+		}
+		serverFallbackStrategy := fallbackStrategy
+		switch {
+		case server.FallbackStrategy != "":
+			serverFallbackStrategy = parseFallbackStrategy(server.FallbackStrategy, false, false)
+		case server.SkipFallback:
+			serverFallbackStrategy = v2ray_dns.FallbackStrategy_Disabled
+		}
+		switch serverFallbackStrategy {
+		case v2ray_dns.FallbackStrategy_Enabled:
+			fallbackServers = append(fallbackServers, tag)
+			matchedFallbackServers = append(matchedFallbackServers, tag)
+		case v2ray_dns.FallbackStrategy_DisabledIfAnyMatch:
+			fallbackServers = append(fallbackServers, tag)
+		}
+		if len(server.Domains) > 0 {
+			var domainRule option.DefaultRule
+			for j, domain := range server.Domains {
+				err = parseDomain(domain, &domainRule)
+				if err != nil {
+					report.Drop(v2box.IndexPath(path+".domains", j), server.Tag, err)
+				}
+			}
+			if dnsRule := v2box.DomainDNSRule(domainRule, tag); dnsRule.IsValid() {
+				dnsOptions.Rules = append(dnsOptions.Rules, option.DNSRule{
+					Type:           C.RuleTypeDefault,
+					DefaultOptions: dnsRule,
+				})
+				domainServers = append(domainServers, tag)
+			}
+		}
+	}
+	localInjected := localTag == "" && !migrateOptions.DisableInjection
+	if localInjected {
+		localTag = serverTags.New("local")
+	}
+	switch {
+	case len(fallbackServers) > 0:
+		dnsOptions.Final = fallbackServers[0]
+		if len(fallbackServers) > 1 {
+			report.Info("dns.servers", dnsConfig.Tag, "unmatched domains are resolved by ", fallbackServers[0], " only, without falling back to ", strings.Join(fallbackServers[1:], ", "))
+		}
+	case len(migratedServers) > 0:
+		dnsOptions.Final = migratedServers[0]
+	case localTag != "":
+		dnsOptions.Final = localTag
+	default:
+		report.Warn("dns.servers", dnsConfig.Tag, "no server is migrated to resolve unmatched domains, and the local DNS server is not injected")
 	}
-	if common.Any(dnsConfig.Servers, func(it *conf_dns.NameServerConfig) bool {
-		return len(it.Domains) > 0 && common.Any(it.Domains, func(it string) bool {
-			return strings.HasSuffix(it, "cn")
+	// a server is not a lost fallback for its own domains
+	lostFallbackServers := common.Filter(matchedFallbackServers, func(fallbackServer string) bool {
+		return common.Any(domainServers, func(domainServer string) bool {
+			return domainServer != fallbackServer
 		})
-	}) {
-		dnsOptions.Rules = append(dnsOptions.Rules, option.DNSRule{
-			Type: C.RuleTypeDefault,
-			DefaultOptions: option.DefaultDNSRule{
-				Domain: []string{"geosite:cn"},
-				Server: "local",
-			},
+	})
+	if len(lostFallbackServers) > 0 {
+		report.Info("dns.servers", dnsConfig.Tag, "matched domains are resolved by their server only, without falling back to ", strings.Join(lostFallbackServers, ", "))
+	}
+	if len(outboundServerRule.Domain) > 0 && len(migratedServers) > 0 {
+		if localTag != "" {
+			// proxy server domains are resolved before other rules send them through the proxy
+			outboundServerRule.Server = localTag
+			dnsOptions.Rules = append([]option.DNSRule{{
+				Type:           C.RuleTypeDefault,
+				DefaultOptions: outboundServerRule,
+			}}, dnsOptions.Rules...)
+		} else {
+			report.Warn("outbounds", "", "proxy server domains are resolved by the DNS rules, as the local DNS server is not injected")
+		}
+	}
+	injectDNS(&dnsOptions, options, outboundTags, localTag, localInjected, directTag, directLoaded)
+	options.DNS = &dnsOptions
+	return hostRoutes
+}
+
+// directOutboundTag returns the tag of the first freedom outbound without redirect or detour,
+// which is reused as the direct outbound of DNS servers, or a free tag for the direct outbound to inject.
+func directOutboundTag(outbounds []option.Outbound, outboundTags v2box.TagSet) (string, bool) {
+	for _, outbound := range outbounds {
+		directOptions := outbound.DirectOptions
+		if outbound.Type == C.TypeDirect && outbound.Tag != "" && directOptions.OverrideAddress == "" && directOptions.OverridePort == 0 && directOptions.Detour == "" {
+			return outbound.Tag, true
+		}
+	}
+	return outboundTags.New("direct"), false
+}
+
+// injectDNS points address resolvers to the local DNS server, and adds the local DNS server and the direct outbound if referenced but missing.
+// The tag taken for the direct outbound is released if it is not injected.
+func injectDNS(dnsOptions *option.DNSOptions, options *option.Options, outboundTags v2box.TagSet, localTag string, localInjected bool, directTag string, directLoaded bool) {
+	for i := range dnsOptions.Servers {
+		if dnsOptions.Servers[i].AddressResolver != "" {
+			dnsOptions.Servers[i].AddressResolver = localTag
+		}
+	}
+	if localInjected && (dnsOptions.Final == localTag || common.Any(dnsOptions.Servers, func(it option.DNSServerOptions) bool {
+		return it.AddressResolver == localTag
+	}) || common.Any(dnsOptions.Rules, func(it option.DNSRule) bool {
+		return it.DefaultOptions.Server == localTag
+	})) {
+		dnsOptions.Servers = append(dnsOptions.Servers, option.DNSServerOptions{
+			Address: "local",
+			Tag:     localTag,
+			Detour:  directTag,
 		})
 	}
-	if !common.Any(options.Outbounds, func(it option.Outbound) bool {
-		return it.Tag == "direct"
+	if directLoaded {
+		return
+	}
+	if common.Any(dnsOptions.Servers, func(it option.DNSServerOptions) bool {
+		return it.Detour == directTag
 	}) {
 		options.Outbounds = append(options.Outbounds, option.Outbound{
 			Type: C.TypeDirect,
-			Tag:  "direct",
+			Tag:  directTag,
 		})
+	} else {
+		delete(outboundTags, directTag)
 	}
-	options.DNS = &dnsOptions
+}
+
+// migrateDNSServer converts a name server address.
+// "+local" servers and DNS over QUIC are queried by V2Ray directly, other servers follow the routing.
+func migrateDNSServer(address *cfgcommon.Address, port uint16, tag string, directTag string) (option.DNSServerOptions, error) {
+	if address == nil {
+		return option.DNSServerOptions{}, v2box.NewPathError("address", "missing server address")
+	}
+	serverOptions := option.DNSServerOptions{
+		Tag: tag,
+	}
+	serverAddress := address.String()
+	var host string
+	switch {
+	case strings.EqualFold(serverAddress, "localhost"):
+		serverOptions.Address = "local"
+		return serverOptions, nil
+	case strings.EqualFold(serverAddress, "fakedns"):
+		return option.DNSServerOptions{}, v2box.WrapPathError("address", errFakeDNSServer)
+	case strings.Contains(serverAddress, "://"):
+		serverURL, err := url.Parse(serverAddress)
+		if err != nil {
+			return option.DNSServerOptions{}, v2box.WrapPathError("address", err)
+		}
+		scheme, isLocal := strings.CutSuffix(serverURL.Scheme, "+local")
+		switch scheme {
+		case "tcp", "https":
+		case "quic":
+			isLocal = true
+		default:
+			return option.DNSServerOptions{}, v2box.NewPathError("address", "unsupported DNS server scheme: ", serverURL.Scheme)
+		}
+		if isLocal {
+			serverOptions.Detour = directTag
+		}
+		serverURL.Scheme = scheme
+		serverOptions.Address = serverURL.String()
+		host = serverURL.Hostname()
+	default:
+		serverOptions.Address = serverAddress
+		if port != 0 && port != 53 {
+			serverOptions.Address = net.JoinHostPort(serverAddress, F.ToString(port))
+		}
+		host = serverAddress
+	}
+	if !M.ParseAddr(host).IsValid() {
+		// V2Ray resolves server domains with the system resolver, pointed to the local DNS server by injectDNS
+		serverOptions.AddressResolver = "local"
+	}
+	return serverOptions, nil
+}
+
+func parseCacheStrategy(cacheStrategy string, disableCache bool) bool {
+	if strings.EqualFold(cacheStrategy, "disabled") {
+		return true
+	}
+	return disableCache
+}
+
+func parseFallbackStrategy(fallbackStrategy string, disableFallback bool, disableFallbackIfMatch bool) v2ray_dns.FallbackStrategy {
+	switch strings.ToLower(fallbackStrategy) {
+	case "disabled":
+		return v2ray_dns.FallbackStrategy_Disabled
+	case "disabledifanymatch", "disabled_if_any_match", "disabled-if-any-match":
+		return v2ray_dns.FallbackStrategy_DisabledIfAnyMatch
+	}
+	switch {
+	case disableFallback:
+		return v2ray_dns.FallbackStrategy_Disabled
+	case disableFallbackIfMatch:
+		return v2ray_dns.FallbackStrategy_DisabledIfAnyMatch
+	}
+	return v2ray_dns.FallbackStrategy_Enabled
 }
 
 func parseStrategy(queryStrategy string) option.DomainStrategy {
@@ -0,0 +1,61 @@
+package v2rayjson
+
+import (
+	"reflect"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+)
+
+func TestMigrateEndpointsGroup(t *testing.T) {
+	options, _ := migrateTest(t, `{
+		"outbounds": [
+			{"protocol": "vmess", "tag": "proxy", "settings": {"vnext": [
+				{"address": "a.example.com", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811"}]},
+				{"address": "b.example.com", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811"}]}
+			]}},
+			{"protocol": "freedom", "tag": "proxy-1"},
+			{"protocol": "trojan", "tag": "users", "settings": {"servers": [
+				{"address": "c.example.com", "port": 443, "password": "a"}
+			]}}
+		],
+		"routing": {"balancers": [{"tag": "balancer", "selector": ["proxy"]}]}
+	}`)
+	group, loaded := findOutbound(options, "proxy")
+	if !loaded || group.Type != C.TypeURLTest {
+		t.Fatalf("expected urltest group proxy, got %+v", group)
+	}
+	if expected := []string{"proxy-1-2", "proxy-2"}; !reflect.DeepEqual(group.URLTestOptions.Outbounds, expected) {
+		t.Errorf("expected endpoints %v, got %v", expected, group.URLTestOptions.Outbounds)
+	}
+	balancer, loaded := findOutbound(options, "balancer")
+	if !loaded {
+		t.Fatal("missing balancer")
+	}
+	if expected := []string{"proxy-1-2", "proxy-2", "proxy-1"}; !reflect.DeepEqual(balancer.SelectorOptions.Outbounds, expected) {
+		t.Errorf("expected balancer outbounds %v, got %v", expected, balancer.SelectorOptions.Outbounds)
+	}
+}
+
+func TestMigrateEndpointsUsers(t *testing.T) {
+	options, _ := migrateTest(t, `{
+		"outbounds": [
+			{"protocol": "socks", "settings": {"servers": [
+				{"address": "a.example.com", "port": 1080, "users": [{"user": "a", "pass": "a"}, {"user": "b", "pass": "b"}]}
+			]}},
+			{"protocol": "socks", "settings": {"servers": [
+				{"address": "b.example.com", "port": 1080, "users": [{"user": "a", "pass": "a"}, {"user": "b", "pass": "b"}]}
+			]}}
+		]
+	}`)
+	if expected := []string{"", "socks-1", "socks-2", "", "socks-1-2", "socks-2-2"}; !reflect.DeepEqual(outboundTags(options), expected) {
+		t.Fatalf("expected outbounds %v, got %v", expected, outboundTags(options))
+	}
+	group := options.Outbounds[0]
+	if group.Type != C.TypeSelector || !reflect.DeepEqual(group.SelectorOptions.Outbounds, []string{"socks-1", "socks-2"}) {
+		t.Errorf("expected selector of the users, got %+v", group)
+	}
+	if options.Outbounds[2].SocksOptions.Username != "b" {
+		t.Errorf("expected user b for socks-2, got %s", options.Outbounds[2].SocksOptions.Username)
+	}
+}
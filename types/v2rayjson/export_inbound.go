@@ -0,0 +1,235 @@
+package v2rayjson
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/auth"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
+)
+
+type exportInboundConfig struct {
+	Protocol       string                `json:"protocol"`
+	Tag            string                `json:"tag,omitempty"`
+	Listen         string                `json:"listen,omitempty"`
+	Port           uint16                `json:"port,omitempty"`
+	Settings       map[string]any        `json:"settings,omitempty"`
+	StreamSettings *exportStreamConfig   `json:"streamSettings,omitempty"`
+	Sniffing       *exportSniffingConfig `json:"sniffing,omitempty"`
+}
+
+type exportSniffingConfig struct {
+	Enabled      bool     `json:"enabled"`
+	DestOverride []string `json:"destOverride,omitempty"`
+}
+
+func exportInbound(inbound option.Inbound, path string, report *v2box.Report) (exportInboundConfig, error) {
+	inboundConfig := exportInboundConfig{
+		Tag:      inbound.Tag,
+		Settings: make(map[string]any),
+	}
+	var (
+		listenOptions    option.ListenOptions
+		tlsOptions       *option.InboundTLSOptions
+		transportOptions *option.V2RayTransportOptions
+		streamConfig     exportStreamConfig
+	)
+	switch inbound.Type {
+	case C.TypeDirect:
+		inboundConfig.Protocol = "dokodemo-door"
+		listenOptions = inbound.DirectOptions.ListenOptions
+		inboundConfig.Settings["network"] = exportNetworkList(inbound.DirectOptions.Network)
+		if inbound.DirectOptions.OverrideAddress != "" {
+			inboundConfig.Settings["address"] = inbound.DirectOptions.OverrideAddress
+		}
+		if inbound.DirectOptions.OverridePort != 0 {
+			inboundConfig.Settings["port"] = inbound.DirectOptions.OverridePort
+		}
+	case C.TypeRedirect:
+		inboundConfig.Protocol = "dokodemo-door"
+		listenOptions = inbound.RedirectOptions.ListenOptions
+		inboundConfig.Settings["network"] = "tcp"
+		inboundConfig.Settings["followRedirect"] = true
+		streamConfig.socket().TProxy = "redirect"
+	case C.TypeTProxy:
+		inboundConfig.Protocol = "dokodemo-door"
+		listenOptions = inbound.TProxyOptions.ListenOptions
+		inboundConfig.Settings["network"] = exportNetworkList(inbound.TProxyOptions.Network)
+		inboundConfig.Settings["followRedirect"] = true
+		streamConfig.socket().TProxy = "tproxy"
+	case C.TypeSocks:
+		inboundConfig.Protocol = "socks"
+		listenOptions = inbound.SocksOptions.ListenOptions
+		exportSocksInbound(inbound.SocksOptions.Users, inboundConfig.Settings)
+	case C.TypeMixed:
+		inboundConfig.Protocol = "socks"
+		listenOptions = inbound.MixedOptions.ListenOptions
+		exportSocksInbound(inbound.MixedOptions.Users, inboundConfig.Settings)
+		report.Warn(path+".type", inbound.Tag, "mixed inbound is exported as socks inbound without HTTP")
+	case C.TypeHTTP:
+		inboundConfig.Protocol = "http"
+		listenOptions = inbound.HTTPOptions.ListenOptions
+		tlsOptions = inbound.HTTPOptions.TLS
+		if len(inbound.HTTPOptions.Users) > 0 {
+			inboundConfig.Settings["accounts"] = exportAccounts(inbound.HTTPOptions.Users)
+		}
+		if inbound.HTTPOptions.SetSystemProxy {
+			report.Warn(path+".set_system_proxy", inbound.Tag, "system proxy is not exported")
+		}
+	case C.TypeShadowsocks:
+		inboundConfig.Protocol = "shadowsocks"
+		listenOptions = inbound.ShadowsocksOptions.ListenOptions
+		if len(inbound.ShadowsocksOptions.Users) > 0 {
+			return exportInboundConfig{}, v2box.NewPathError("users", "multi-user shadowsocks is not supported by V2Ray")
+		}
+		if len(inbound.ShadowsocksOptions.Destinations) > 0 {
+			return exportInboundConfig{}, v2box.NewPathError("destinations", "shadowsocks relay is not supported by V2Ray")
+		}
+		err := checkShadowsocksMethod(inbound.ShadowsocksOptions.Method)
+		if err != nil {
+			return exportInboundConfig{}, err
+		}
+		inboundConfig.Settings["method"] = inbound.ShadowsocksOptions.Method
+		inboundConfig.Settings["password"] = inbound.ShadowsocksOptions.Password
+		inboundConfig.Settings["network"] = exportNetworkList(inbound.ShadowsocksOptions.Network)
+	case C.TypeVMess:
+		inboundConfig.Protocol = "vmess"
+		listenOptions = inbound.VMessOptions.ListenOptions
+		tlsOptions = inbound.VMessOptions.TLS
+		transportOptions = inbound.VMessOptions.Transport
+		clients := make([]map[string]any, 0, len(inbound.VMessOptions.Users))
+		for _, user := range inbound.VMessOptions.Users {
+			clients = append(clients, exportClient(user.Name, map[string]any{
+				"id":      user.UUID,
+				"alterId": user.AlterId,
+			}))
+		}
+		inboundConfig.Settings["clients"] = clients
+	case C.TypeTrojan:
+		inboundConfig.Protocol = "trojan"
+		listenOptions = inbound.TrojanOptions.ListenOptions
+		tlsOptions = inbound.TrojanOptions.TLS
+		transportOptions = inbound.TrojanOptions.Transport
+		clients := make([]map[string]any, 0, len(inbound.TrojanOptions.Users))
+		for _, user := range inbound.TrojanOptions.Users {
+			clients = append(clients, exportClient(user.Name, map[string]any{
+				"password": user.Password,
+			}))
+		}
+		inboundConfig.Settings["clients"] = clients
+		var fallbacks []map[string]any
+		if fallback := inbound.TrojanOptions.Fallback; fallback != nil {
+			fallbacks = append(fallbacks, map[string]any{
+				"dest": M.ParseSocksaddrHostPort(fallback.Server, fallback.ServerPort).String(),
+			})
+		}
+		alpnList := make([]string, 0, len(inbound.TrojanOptions.FallbackForALPN))
+		for alpn := range inbound.TrojanOptions.FallbackForALPN {
+			alpnList = append(alpnList, alpn)
+		}
+		sort.Strings(alpnList)
+		for _, alpn := range alpnList {
+			fallback := inbound.TrojanOptions.FallbackForALPN[alpn]
+			fallbacks = append(fallbacks, map[string]any{
+				"alpn": alpn,
+				"dest": M.ParseSocksaddrHostPort(fallback.Server, fallback.ServerPort).String(),
+			})
+		}
+		if len(fallbacks) > 0 {
+			inboundConfig.Settings["fallbacks"] = fallbacks
+		}
+	case C.TypeVLESS:
+		inboundConfig.Protocol = "vless"
+		listenOptions = inbound.VLESSOptions.ListenOptions
+		tlsOptions = inbound.VLESSOptions.TLS
+		transportOptions = inbound.VLESSOptions.Transport
+		clients := make([]map[string]any, 0, len(inbound.VLESSOptions.Users))
+		for i, user := range inbound.VLESSOptions.Users {
+			if user.Flow != "" {
+				report.Warn(v2box.JoinPath(v2box.IndexPath(path+".users", i), "flow"), inbound.Tag, "flow is not supported by V2Ray")
+			}
+			clients = append(clients, exportClient(user.Name, map[string]any{
+				"id": user.UUID,
+			}))
+		}
+		inboundConfig.Settings["clients"] = clients
+		inboundConfig.Settings["decryption"] = "none"
+	default:
+		return exportInboundConfig{}, v2box.NewPathError("type", inbound.Type, " inbound is not supported by V2Ray")
+	}
+	if listenOptions.Listen != nil {
+		inboundConfig.Listen = netip.Addr(*listenOptions.Listen).String()
+	}
+	inboundConfig.Port = listenOptions.ListenPort
+	if listenOptions.TCPFastOpen {
+		streamConfig.socket().TCPFastOpen = true
+	}
+	if listenOptions.ProxyProtocol {
+		streamConfig.socket().AcceptProxyProtocol = true
+	}
+	if listenOptions.Detour != "" {
+		report.Warn(path+".detour", inbound.Tag, "inbound detour is not exported")
+	}
+	if listenOptions.DomainStrategy != 0 {
+		report.Warn(path+".domain_strategy", inbound.Tag, "domain strategy is not exported")
+	}
+	if listenOptions.SniffEnabled {
+		inboundConfig.Sniffing = &exportSniffingConfig{
+			Enabled:      true,
+			DestOverride: []string{"http", "tls"},
+		}
+		if !listenOptions.SniffOverrideDestination {
+			report.Warn(path+".sniff", inbound.Tag, "V2Ray overrides the destination with the sniffed domain")
+		}
+	}
+	if transportOptions != nil {
+		exportTransport(*transportOptions, &streamConfig)
+	}
+	if tlsOptions != nil && tlsOptions.Enabled {
+		tlsConfig, err := exportInboundTLS(*tlsOptions, path+".tls", inbound.Tag, report)
+		if err != nil {
+			return exportInboundConfig{}, v2box.WrapPathError("tls", err)
+		}
+		streamConfig.Security = "tls"
+		streamConfig.TLSSettings = tlsConfig
+	}
+	if !streamConfig.isEmpty() {
+		inboundConfig.StreamSettings = &streamConfig
+	}
+	return inboundConfig, nil
+}
+
+func exportSocksInbound(users []auth.User, settings map[string]any) {
+	settings["auth"] = "noauth"
+	if len(users) > 0 {
+		settings["auth"] = "password"
+		settings["accounts"] = exportAccounts(users)
+	}
+	settings["udp"] = true
+}
+
+func exportAccounts(users []auth.User) []map[string]any {
+	accounts := make([]map[string]any, 0, len(users))
+	for _, user := range users {
+		accounts = append(accounts, map[string]any{
+			"user": user.Username,
+			"pass": user.Password,
+		})
+	}
+	return accounts
+}
+
+func exportClient(name string, client map[string]any) map[string]any {
+	if name != "" {
+		client["email"] = name
+	}
+	return client
+}
+
+func exportNetworkList(networkList option.NetworkList) string {
+	return strings.Join(networkList.Build(), ",")
+}
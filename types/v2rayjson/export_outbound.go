@@ -0,0 +1,241 @@
+package v2rayjson
+
+import (
+	"net/netip"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
+)
+
+type exportOutboundConfig struct {
+	Protocol       string              `json:"protocol"`
+	Tag            string              `json:"tag,omitempty"`
+	SendThrough    string              `json:"sendThrough,omitempty"`
+	Settings       map[string]any      `json:"settings,omitempty"`
+	StreamSettings *exportStreamConfig `json:"streamSettings,omitempty"`
+	ProxySettings  *exportProxyConfig  `json:"proxySettings,omitempty"`
+}
+
+type exportProxyConfig struct {
+	Tag                 string `json:"tag"`
+	TransportLayerProxy bool   `json:"transportLayer,omitempty"`
+}
+
+func exportOutbound(outbound option.Outbound, path string, report *v2box.Report) (exportOutboundConfig, error) {
+	outboundConfig := exportOutboundConfig{
+		Tag:      outbound.Tag,
+		Settings: make(map[string]any),
+	}
+	var (
+		dialerOptions    option.DialerOptions
+		tlsOptions       *option.OutboundTLSOptions
+		transportOptions *option.V2RayTransportOptions
+		multiplexOptions *option.MultiplexOptions
+		streamConfig     exportStreamConfig
+	)
+	switch outbound.Type {
+	case C.TypeDirect:
+		outboundConfig.Protocol = "freedom"
+		dialerOptions = outbound.DirectOptions.DialerOptions
+		if domainStrategy := exportDomainStrategy(dialerOptions.DomainStrategy); domainStrategy != "" {
+			outboundConfig.Settings["domainStrategy"] = domainStrategy
+			dialerOptions.DomainStrategy = 0
+		}
+		if outbound.DirectOptions.OverrideAddress != "" || outbound.DirectOptions.OverridePort != 0 {
+			outboundConfig.Settings["redirect"] = M.ParseSocksaddrHostPort(outbound.DirectOptions.OverrideAddress, outbound.DirectOptions.OverridePort).String()
+		}
+		if outbound.DirectOptions.ProxyProtocol != 0 {
+			report.Warn(path+".proxy_protocol", outbound.Tag, "proxy protocol is not exported")
+		}
+	case C.TypeBlock:
+		outboundConfig.Protocol = "blackhole"
+	case C.TypeDNS:
+		outboundConfig.Protocol = "dns"
+	case C.TypeSocks:
+		outboundConfig.Protocol = "socks"
+		socksOptions := outbound.SocksOptions
+		dialerOptions = socksOptions.DialerOptions
+		server := exportServer(socksOptions.ServerOptions)
+		if socksOptions.Username != "" {
+			server["users"] = []map[string]any{{
+				"user": socksOptions.Username,
+				"pass": socksOptions.Password,
+			}}
+		}
+		outboundConfig.Settings["servers"] = []map[string]any{server}
+		if socksOptions.Version != "" {
+			outboundConfig.Settings["version"] = socksOptions.Version
+		}
+		if socksOptions.UDPOverTCPOptions != nil && socksOptions.UDPOverTCPOptions.Enabled {
+			report.Warn(path+".udp_over_tcp", outbound.Tag, "UDP over TCP is not exported")
+		}
+	case C.TypeHTTP:
+		outboundConfig.Protocol = "http"
+		httpOptions := outbound.HTTPOptions
+		dialerOptions = httpOptions.DialerOptions
+		tlsOptions = httpOptions.TLS
+		server := exportServer(httpOptions.ServerOptions)
+		if httpOptions.Username != "" {
+			server["users"] = []map[string]any{{
+				"user": httpOptions.Username,
+				"pass": httpOptions.Password,
+			}}
+		}
+		outboundConfig.Settings["servers"] = []map[string]any{server}
+	case C.TypeShadowsocks:
+		outboundConfig.Protocol = "shadowsocks"
+		shadowsocksOptions := outbound.ShadowsocksOptions
+		dialerOptions = shadowsocksOptions.DialerOptions
+		multiplexOptions = shadowsocksOptions.MultiplexOptions
+		if shadowsocksOptions.Plugin != "" {
+			return exportOutboundConfig{}, v2box.NewPathError("plugin", "shadowsocks plugin is not supported by V2Ray")
+		}
+		err := checkShadowsocksMethod(shadowsocksOptions.Method)
+		if err != nil {
+			return exportOutboundConfig{}, err
+		}
+		server := exportServer(shadowsocksOptions.ServerOptions)
+		server["method"] = shadowsocksOptions.Method
+		server["password"] = shadowsocksOptions.Password
+		outboundConfig.Settings["servers"] = []map[string]any{server}
+		if shadowsocksOptions.UDPOverTCPOptions != nil && shadowsocksOptions.UDPOverTCPOptions.Enabled {
+			report.Warn(path+".udp_over_tcp", outbound.Tag, "UDP over TCP is not exported")
+		}
+	case C.TypeVMess:
+		outboundConfig.Protocol = "vmess"
+		vmessOptions := outbound.VMessOptions
+		dialerOptions = vmessOptions.DialerOptions
+		tlsOptions = vmessOptions.TLS
+		transportOptions = vmessOptions.Transport
+		multiplexOptions = vmessOptions.Multiplex
+		user := map[string]any{
+			"id":       vmessOptions.UUID,
+			"alterId":  vmessOptions.AlterId,
+			"security": vmessOptions.Security,
+		}
+		if vmessOptions.AuthenticatedLength {
+			user["experiments"] = "AuthenticatedLength"
+		}
+		server := exportServer(vmessOptions.ServerOptions)
+		server["users"] = []map[string]any{user}
+		outboundConfig.Settings["vnext"] = []map[string]any{server}
+		if vmessOptions.GlobalPadding {
+			report.Warn(path+".global_padding", outbound.Tag, "global padding is not exported")
+		}
+		if vmessOptions.PacketEncoding != "" {
+			report.Warn(path+".packet_encoding", outbound.Tag, "packet encoding is not exported")
+		}
+	case C.TypeTrojan:
+		outboundConfig.Protocol = "trojan"
+		trojanOptions := outbound.TrojanOptions
+		dialerOptions = trojanOptions.DialerOptions
+		tlsOptions = trojanOptions.TLS
+		transportOptions = trojanOptions.Transport
+		multiplexOptions = trojanOptions.Multiplex
+		server := exportServer(trojanOptions.ServerOptions)
+		server["password"] = trojanOptions.Password
+		outboundConfig.Settings["servers"] = []map[string]any{server}
+	case C.TypeVLESS:
+		outboundConfig.Protocol = "vless"
+		vlessOptions := outbound.VLESSOptions
+		dialerOptions = vlessOptions.DialerOptions
+		tlsOptions = vlessOptions.TLS
+		transportOptions = vlessOptions.Transport
+		if vlessOptions.Flow != "" {
+			report.Warn(path+".flow", outbound.Tag, "flow is not supported by V2Ray")
+		}
+		if vlessOptions.PacketEncoding != nil && *vlessOptions.PacketEncoding != "" {
+			report.Warn(path+".packet_encoding", outbound.Tag, "packet encoding is not exported")
+		}
+		server := exportServer(vlessOptions.ServerOptions)
+		server["users"] = []map[string]any{{
+			"id":         vlessOptions.UUID,
+			"encryption": "none",
+		}}
+		outboundConfig.Settings["vnext"] = []map[string]any{server}
+	default:
+		return exportOutboundConfig{}, v2box.NewPathError("type", outbound.Type, " outbound is not supported by V2Ray")
+	}
+	if multiplexOptions != nil && multiplexOptions.Enabled {
+		report.Warn(path+".multiplex", outbound.Tag, "multiplex is not compatible with V2Ray mux and is not exported")
+	}
+	if transportOptions != nil {
+		exportTransport(*transportOptions, &streamConfig)
+	}
+	if tlsOptions != nil && tlsOptions.Enabled {
+		tlsConfig, err := exportOutboundTLS(*tlsOptions, path+".tls", outbound.Tag, report)
+		if err != nil {
+			return exportOutboundConfig{}, v2box.WrapPathError("tls", err)
+		}
+		streamConfig.Security = "tls"
+		streamConfig.TLSSettings = tlsConfig
+	}
+	exportDialer(dialerOptions, &outboundConfig, &streamConfig, path, report)
+	if !streamConfig.isEmpty() {
+		outboundConfig.StreamSettings = &streamConfig
+	}
+	return outboundConfig, nil
+}
+
+func exportDialer(dialerOptions option.DialerOptions, outboundConfig *exportOutboundConfig, streamConfig *exportStreamConfig, path string, report *v2box.Report) {
+	if dialerOptions.Detour != "" {
+		outboundConfig.ProxySettings = &exportProxyConfig{
+			Tag:                 dialerOptions.Detour,
+			TransportLayerProxy: true,
+		}
+	}
+	if dialerOptions.BindInterface != "" {
+		streamConfig.socket().BindToDevice = dialerOptions.BindInterface
+	}
+	if dialerOptions.RoutingMark != 0 {
+		streamConfig.socket().Mark = dialerOptions.RoutingMark
+	}
+	if dialerOptions.TCPFastOpen {
+		streamConfig.socket().TCPFastOpen = true
+	}
+	if dialerOptions.Inet4BindAddress != nil {
+		outboundConfig.SendThrough = netip.Addr(*dialerOptions.Inet4BindAddress).String()
+		if dialerOptions.Inet6BindAddress != nil {
+			report.Warn(path+".inet6_bind_address", outboundConfig.Tag, "only one bind address is exported")
+		}
+	} else if dialerOptions.Inet6BindAddress != nil {
+		outboundConfig.SendThrough = netip.Addr(*dialerOptions.Inet6BindAddress).String()
+	}
+	if dialerOptions.DomainStrategy != 0 {
+		report.Warn(path+".domain_strategy", outboundConfig.Tag, "domain strategy is not exported")
+	}
+	if dialerOptions.ConnectTimeout != 0 || dialerOptions.FallbackDelay != 0 || dialerOptions.ReuseAddr || dialerOptions.ProtectPath != "" {
+		report.Warn(path, outboundConfig.Tag, "connect timeout, fallback delay, reuse address and protect path are not exported")
+	}
+}
+
+func exportServer(serverOptions option.ServerOptions) map[string]any {
+	return map[string]any{
+		"address": serverOptions.Server,
+		"port":    serverOptions.ServerPort,
+	}
+}
+
+func exportDomainStrategy(domainStrategy option.DomainStrategy) string {
+	switch dns.DomainStrategy(domainStrategy) {
+	case dns.DomainStrategyPreferIPv4, dns.DomainStrategyPreferIPv6:
+		return "UseIP"
+	case dns.DomainStrategyUseIPv4:
+		return "UseIPv4"
+	case dns.DomainStrategyUseIPv6:
+		return "UseIPv6"
+	default:
+		return ""
+	}
+}
+
+func checkShadowsocksMethod(method string) error {
+	if !common.Contains([]string{"aes-128-gcm", "aes-256-gcm", "chacha20-poly1305", "chacha20-ietf-poly1305", "none", "plain"}, method) {
+		return v2box.NewPathError("method", "shadowsocks method ", method, " is not supported by V2Ray")
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package v2rayjson
+
+import (
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/v2box"
+)
+
+type exportStreamConfig struct {
+	Network        string              `json:"network,omitempty"`
+	Security       string              `json:"security,omitempty"`
+	TLSSettings    *exportTLSConfig    `json:"tlsSettings,omitempty"`
+	WSSettings     map[string]any      `json:"wsSettings,omitempty"`
+	HTTPSettings   map[string]any      `json:"httpSettings,omitempty"`
+	GRPCSettings   map[string]any      `json:"grpcSettings,omitempty"`
+	SocketSettings *exportSocketConfig `json:"sockopt,omitempty"`
+}
+
+type exportSocketConfig struct {
+	Mark                int    `json:"mark,omitempty"`
+	TCPFastOpen         bool   `json:"tcpFastOpen,omitempty"`
+	TProxy              string `json:"tproxy,omitempty"`
+	AcceptProxyProtocol bool   `json:"acceptProxyProtocol,omitempty"`
+	BindToDevice        string `json:"bindToDevice,omitempty"`
+}
+
+type exportTLSConfig struct {
+	ServerName   string                    `json:"serverName,omitempty"`
+	Insecure     bool                      `json:"allowInsecure,omitempty"`
+	ALPN         []string                  `json:"alpn,omitempty"`
+	Certificates []exportCertificateConfig `json:"certificates,omitempty"`
+}
+
+type exportCertificateConfig struct {
+	CertificateFile string   `json:"certificateFile,omitempty"`
+	Certificate     []string `json:"certificate,omitempty"`
+	KeyFile         string   `json:"keyFile,omitempty"`
+	Key             []string `json:"key,omitempty"`
+	Usage           string   `json:"usage,omitempty"`
+}
+
+func (c *exportStreamConfig) socket() *exportSocketConfig {
+	if c.SocketSettings == nil {
+		c.SocketSettings = &exportSocketConfig{}
+	}
+	return c.SocketSettings
+}
+
+func (c *exportStreamConfig) isEmpty() bool {
+	return c.Network == "" && c.Security == "" && c.SocketSettings == nil
+}
+
+func exportTransport(transportOptions option.V2RayTransportOptions, streamConfig *exportStreamConfig) {
+	switch transportOptions.Type {
+	case C.V2RayTransportTypeHTTP:
+		streamConfig.Network = "http"
+		httpSettings := make(map[string]any)
+		if len(transportOptions.HTTPOptions.Host) > 0 {
+			httpSettings["host"] = transportOptions.HTTPOptions.Host
+		}
+		if transportOptions.HTTPOptions.Path != "" {
+			httpSettings["path"] = transportOptions.HTTPOptions.Path
+		}
+		if transportOptions.HTTPOptions.Method != "" {
+			httpSettings["method"] = transportOptions.HTTPOptions.Method
+		}
+		if len(transportOptions.HTTPOptions.Headers) > 0 {
+			httpSettings["headers"] = transportOptions.HTTPOptions.Headers
+		}
+		streamConfig.HTTPSettings = httpSettings
+	case C.V2RayTransportTypeWebsocket:
+		streamConfig.Network = "ws"
+		wsSettings := make(map[string]any)
+		if transportOptions.WebsocketOptions.Path != "" {
+			wsSettings["path"] = transportOptions.WebsocketOptions.Path
+		}
+		if len(transportOptions.WebsocketOptions.Headers) > 0 {
+			wsSettings["headers"] = transportOptions.WebsocketOptions.Headers
+		}
+		if transportOptions.WebsocketOptions.MaxEarlyData > 0 {
+			wsSettings["maxEarlyData"] = transportOptions.WebsocketOptions.MaxEarlyData
+			wsSettings["earlyDataHeaderName"] = transportOptions.WebsocketOptions.EarlyDataHeaderName
+		}
+		streamConfig.WSSettings = wsSettings
+	case C.V2RayTransportTypeQUIC:
+		streamConfig.Network = "quic"
+	case C.V2RayTransportTypeGRPC:
+		streamConfig.Network = "grpc"
+		streamConfig.GRPCSettings = map[string]any{
+			"serviceName": transportOptions.GRPCOptions.ServiceName,
+		}
+	}
+}
+
+func exportInboundTLS(tlsOptions option.InboundTLSOptions, path string, tag string, report *v2box.Report) (*exportTLSConfig, error) {
+	if tlsOptions.ACME != nil && len(tlsOptions.ACME.Domain) > 0 {
+		return nil, v2box.NewPathError("acme", "ACME is not supported by V2Ray")
+	}
+	if tlsOptions.Reality != nil && tlsOptions.Reality.Enabled {
+		return nil, v2box.NewPathError("reality", "reality is not supported by V2Ray")
+	}
+	if tlsOptions.MinVersion != "" || tlsOptions.MaxVersion != "" || len(tlsOptions.CipherSuites) > 0 {
+		report.Warn(path, tag, "TLS versions and cipher suites are not exported")
+	}
+	tlsConfig := exportTLSConfig{
+		ServerName: tlsOptions.ServerName,
+		ALPN:       tlsOptions.ALPN,
+	}
+	certificate := exportCertificateConfig{
+		CertificateFile: tlsOptions.CertificatePath,
+		KeyFile:         tlsOptions.KeyPath,
+	}
+	if tlsOptions.Certificate != "" {
+		certificate.Certificate = strings.Split(tlsOptions.Certificate, "\n")
+	}
+	if tlsOptions.Key != "" {
+		certificate.Key = strings.Split(tlsOptions.Key, "\n")
+	}
+	if certificate.CertificateFile != "" || len(certificate.Certificate) > 0 {
+		tlsConfig.Certificates = []exportCertificateConfig{certificate}
+	}
+	return &tlsConfig, nil
+}
+
+func exportOutboundTLS(tlsOptions option.OutboundTLSOptions, path string, tag string, report *v2box.Report) (*exportTLSConfig, error) {
+	if tlsOptions.Reality != nil && tlsOptions.Reality.Enabled {
+		return nil, v2box.NewPathError("reality", "reality is not supported by V2Ray")
+	}
+	if tlsOptions.UTLS != nil && tlsOptions.UTLS.Enabled {
+		report.Warn(path+".utls", tag, "uTLS is not exported")
+	}
+	if tlsOptions.ECH != nil && tlsOptions.ECH.Enabled {
+		report.Warn(path+".ech", tag, "ECH is not exported")
+	}
+	if tlsOptions.DisableSNI {
+		report.Warn(path+".disable_sni", tag, "disable SNI is not exported")
+	}
+	if tlsOptions.MinVersion != "" || tlsOptions.MaxVersion != "" || len(tlsOptions.CipherSuites) > 0 {
+		report.Warn(path, tag, "TLS versions and cipher suites are not exported")
+	}
+	tlsConfig := exportTLSConfig{
+		ServerName: tlsOptions.ServerName,
+		Insecure:   tlsOptions.Insecure,
+		ALPN:       tlsOptions.ALPN,
+	}
+	certificate := exportCertificateConfig{
+		CertificateFile: tlsOptions.CertificatePath,
+		Usage:           "verify",
+	}
+	if tlsOptions.Certificate != "" {
+		certificate.Certificate = strings.Split(tlsOptions.Certificate, "\n")
+	}
+	if certificate.CertificateFile != "" || len(certificate.Certificate) > 0 {
+		tlsConfig.Certificates = []exportCertificateConfig{certificate}
+	}
+	return &tlsConfig, nil
+}
@@ -0,0 +1,177 @@
+package v2rayjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/v2box"
+)
+
+const exportTestConfig = `{
+	"dns": {
+		"servers": [
+			{"tag": "remote", "address": "https://1.1.1.1/dns-query"},
+			{"tag": "local", "address": "223.5.5.5"}
+		],
+		"rules": [{"domain_suffix": [".cn"], "server": "local"}],
+		"final": "remote"
+	},
+	"inbounds": [
+		{"type": "socks", "tag": "socks-in", "listen": "127.0.0.1", "listen_port": 1080},
+		{"type": "http", "tag": "http-in", "listen": "127.0.0.1", "listen_port": 8080, "users": [{"username": "a", "password": "b"}]},
+		{"type": "shadowsocks", "tag": "ss-in", "listen": "::", "listen_port": 8388, "method": "aes-128-gcm", "password": "a"}
+	],
+	"outbounds": [
+		{"type": "vmess", "tag": "proxy", "server": "a.example.com", "server_port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811",
+			"tls": {"enabled": true, "server_name": "a.example.com"}, "transport": {"type": "ws", "path": "/ws"}},
+		{"type": "trojan", "tag": "trojan", "server": "b.example.com", "server_port": 443, "password": "a",
+			"tls": {"enabled": true, "server_name": "b.example.com"}, "transport": {"type": "grpc", "service_name": "a"}},
+		{"type": "shadowsocks", "tag": "ss", "server": "c.example.com", "server_port": 8388, "method": "aes-256-gcm", "password": "a"},
+		{"type": "direct", "tag": "direct"},
+		{"type": "block", "tag": "block"}
+	],
+	"route": {
+		"rules": [
+			{"domain": ["ads.com"], "domain_suffix": [".ads.com"], "outbound": "block"},
+			{"domain_keyword": ["google"], "ip_cidr": ["8.8.8.8/32"], "port": [443], "network": "tcp", "outbound": "proxy"},
+			{"geosite": ["cn"], "geoip": ["cn"], "outbound": "direct"},
+			{"inbound": ["socks-in"], "outbound": "ss"}
+		]
+	}
+}`
+
+func roundTripTest(t *testing.T, content string) (option.Options, option.Options) {
+	t.Helper()
+	var options option.Options
+	err := json.Unmarshal([]byte(content), &options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, _, err := Export(options, log.StdLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, report, err := Migrate(exported, v2box.MigrateOptions{}, log.StdLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return options, migrated
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	options, migrated := roundTripTest(t, exportTestConfig)
+	if !reflect.DeepEqual(migrated.Inbounds, options.Inbounds) {
+		t.Errorf("expected inbounds %+v, got %+v", options.Inbounds, migrated.Inbounds)
+	}
+	if !reflect.DeepEqual(migrated.Outbounds, options.Outbounds) {
+		t.Errorf("expected outbounds %+v, got %+v", options.Outbounds, migrated.Outbounds)
+	}
+}
+
+func TestExportRoundTripRules(t *testing.T) {
+	_, migrated := roundTripTest(t, exportTestConfig)
+	// rules with domain and IP items are split, as V2Ray requires both to match.
+	var expected []option.Rule
+	err := json.Unmarshal([]byte(`[
+		{"domain": ["ads.com"], "domain_suffix": [".ads.com"], "outbound": "block"},
+		{"domain_keyword": ["google"], "port": [443], "network": "tcp", "outbound": "proxy"},
+		{"ip_cidr": ["8.8.8.8/32"], "port": [443], "network": "tcp", "outbound": "proxy"},
+		{"geosite": ["cn"], "outbound": "direct"},
+		{"geoip": ["cn"], "outbound": "direct"},
+		{"inbound": ["socks-in"], "outbound": "ss"}
+	]`), &expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated.Route == nil || !reflect.DeepEqual(migrated.Route.Rules, expected) {
+		t.Errorf("expected rules %+v, got %+v", expected, migrated.Route)
+	}
+}
+
+func TestExportRoundTripDNS(t *testing.T) {
+	_, migrated := roundTripTest(t, exportTestConfig)
+	if migrated.DNS == nil {
+		t.Fatal("missing DNS")
+	}
+	serverAddresses := make(map[string]string)
+	for _, server := range migrated.DNS.Servers {
+		serverAddresses[server.Tag] = server.Address
+	}
+	if address := serverAddresses[migrated.DNS.Final]; address != "https://1.1.1.1/dns-query" {
+		t.Errorf("expected the final server to be the remote server, got %s", address)
+	}
+	var cnServer string
+	for _, rule := range migrated.DNS.Rules {
+		if reflect.DeepEqual([]string(rule.DefaultOptions.DomainRegex), []string{`\.cn$`}) {
+			cnServer = rule.DefaultOptions.Server
+		}
+	}
+	if address := serverAddresses[cnServer]; address != "223.5.5.5" {
+		t.Errorf("expected .cn to be resolved by the local server, got %s", address)
+	}
+}
+
+func TestExportRule(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		rule     string
+		expected []fieldRuleV4
+		err      bool
+	}{
+		{
+			name:     "domain",
+			rule:     `{"domain": ["a.com"], "domain_suffix": [".a.com", "b.com"], "outbound": "proxy"}`,
+			expected: []fieldRuleV4{{Type: "field", OutboundTag: "proxy", Domain: []string{"domain:a.com", `regexp:b\.com$`}}},
+		},
+		{
+			name:     "ip",
+			rule:     `{"geoip": ["cn"], "ip_cidr": ["10.0.0.0/8"], "outbound": "direct"}`,
+			expected: []fieldRuleV4{{Type: "field", OutboundTag: "direct", IP: []string{"geoip:cn", "10.0.0.0/8"}}},
+		},
+		{
+			name: "domain and ip",
+			rule: `{"domain_keyword": ["a"], "ip_cidr": ["10.0.0.0/8"], "port_range": ["1000:"], "outbound": "direct"}`,
+			expected: []fieldRuleV4{
+				{Type: "field", OutboundTag: "direct", Domain: []string{"keyword:a"}, Port: "1000-65535"},
+				{Type: "field", OutboundTag: "direct", IP: []string{"10.0.0.0/8"}, Port: "1000-65535"},
+			},
+		},
+		{
+			name: "unsupported item",
+			rule: `{"process_name": ["a"], "outbound": "direct"}`,
+			err:  true,
+		},
+		{
+			name: "logical",
+			rule: `{"type": "logical", "mode": "and", "rules": [{"domain": ["a.com"]}], "outbound": "direct"}`,
+			err:  true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			var rule option.Rule
+			err := json.Unmarshal([]byte(testCase.rule), &rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fieldRules, err := exportRule(rule)
+			if testCase.err {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", fieldRules)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fieldRules, testCase.expected) {
+				t.Errorf("expected %+v, got %+v", testCase.expected, fieldRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package v2rayjson
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/v2box"
+
+	"github.com/v2fly/v2ray-core/v5/app/dns/fakedns"
+	conf_dns "github.com/v2fly/v2ray-core/v5/infra/conf/synthetic/dns"
+)
+
+var errFakeDNSServer = E.New("fakedns is not supported, domains of the server are resolved by other servers with real addresses")
+
+// reportFakeDNS reports FakeDNS pools, the sing-box version migrated to has no FakeIP.
+// DNS queries are answered with real addresses instead, transparent proxies have to sniff the destination domain.
+func reportFakeDNS(fakeDNSConfig *conf_dns.FakeDNSConfig, path string, tag string, report *v2box.Report) {
+	pools, err := fakeDNSConfig.Build()
+	if err != nil {
+		report.Drop(path, tag, err)
+		return
+	}
+	reportFakeDNSPools(pools, path, tag, report)
+}
+
+func reportFakeDNSPools(pools *fakedns.FakeDnsPoolMulti, path string, tag string, report *v2box.Report) {
+	if pools == nil || len(pools.Pools) == 0 {
+		return
+	}
+	poolRanges := common.Map(pools.Pools, func(it *fakedns.FakeDnsPool) string {
+		return F.ToString(it.IpPool, " (size ", it.LruSize, ")")
+	})
+	report.Drop(path, tag, E.New("FakeIP is not supported, pools ", strings.Join(poolRanges, ", "), " are not migrated"))
+}
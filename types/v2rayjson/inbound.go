@@ -1,15 +1,19 @@
 package v2rayjson
 
 import (
+	"bytes"
 	"reflect"
 	"strings"
 	_ "unsafe"
 
+	"github.com/sagernet/sing-box/common/json"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
 
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
@@ -29,7 +33,30 @@ import (
 //go:linkname inboundConfigLoader github.com/v2fly/v2ray-core/v5/infra/conf/v4.inboundConfigLoader
 var inboundConfigLoader *loader.JSONConfigLoader
 
-func migrateInbound(inboundConfig v4json.InboundDetourConfig) (option.Inbound, error) {
+// parseSockoptDomainStrategy decodes the sockopt domain strategy of inbounds by index, as SocketConfig does not support it.
+func parseSockoptDomainStrategy(content []byte) ([]string, error) {
+	var config struct {
+		Inbounds []struct {
+			StreamSettings struct {
+				SocketSettings struct {
+					DomainStrategy string `json:"domainStrategy"`
+				} `json:"sockopt"`
+			} `json:"streamSettings"`
+		} `json:"inbounds"`
+	}
+	decoder := json.NewDecoder(json.NewCommentFilter(bytes.NewReader(content)))
+	err := decoder.Decode(&config)
+	if err != nil {
+		return nil, err
+	}
+	domainStrategies := make([]string, len(config.Inbounds))
+	for i, inbound := range config.Inbounds {
+		domainStrategies[i] = inbound.StreamSettings.SocketSettings.DomainStrategy
+	}
+	return domainStrategies, nil
+}
+
+func migrateInbound(inboundConfig v4json.InboundDetourConfig, domainStrategy string, inboundTags v2box.TagSet, maxPorts int, path string, report *v2box.Report) ([]option.Inbound, error) {
 	var inbound option.Inbound
 	inbound.Tag = inboundConfig.Tag
 
@@ -37,15 +64,17 @@ func migrateInbound(inboundConfig v4json.InboundDetourConfig) (option.Inbound, e
 	if inboundConfig.ListenOn != nil {
 		listenOptions.Listen = option.NewListenAddress(M.ParseAddr(inboundConfig.ListenOn.Address.String()))
 	}
-	if inboundConfig.PortRange != nil {
-		listenOptions.ListenPort = uint16(inboundConfig.PortRange.From)
-	}
+	listenOptions.InboundOptions = parseSniffing(inboundConfig.SniffingConfig, path+".sniffing", inbound.Tag, report)
 
 	var tlsOptions option.InboundTLSOptions
 	var transportOptions option.V2RayTransportOptions
 	var tproxyName string
 	var err error
 
+	if domainStrategy != "" && !strings.EqualFold(domainStrategy, "AsIs") {
+		report.Info(path+".streamSettings.sockopt.domainStrategy", inbound.Tag, "domain strategy is not supported by V2Ray inbounds and is ignored")
+	}
+
 	if inboundConfig.StreamSetting != nil {
 		streamSettings := inboundConfig.StreamSetting
 		if socketSettings := streamSettings.SocketSettings; socketSettings != nil {
@@ -60,31 +89,15 @@ func migrateInbound(inboundConfig v4json.InboundDetourConfig) (option.Inbound, e
 		}
 		transportOptions, err = parseTransport(streamSettings)
 		if err != nil {
-			return option.Inbound{}, err
+			return nil, err
 		}
 		if security := streamSettings.Security; security != "" {
 			switch security {
+			case "none":
 			case "tls":
-				tlsOptions.Enabled = true
-				if tlsSettings := streamSettings.TLSSettings; tlsSettings != nil {
-					tlsOptions.ServerName = tlsSettings.ServerName
-					for _, certConfig := range tlsSettings.Certs {
-						if certConfig.Usage != "" && certConfig.Usage != "encipherment" {
-							continue
-						}
-						if len(certConfig.CertStr) > 0 {
-							tlsOptions.Certificate = strings.Join(certConfig.CertStr, "\n")
-						}
-						if len(certConfig.KeyStr) > 0 {
-							tlsOptions.Key = strings.Join(certConfig.KeyStr, "\n")
-						}
-						tlsOptions.CertificatePath = certConfig.CertFile
-						tlsOptions.KeyPath = certConfig.KeyFile
-					}
-					if tlsSettings.ALPN != nil && tlsSettings.ALPN.Len() > 0 {
-						tlsOptions.ALPN = []string(*tlsSettings.ALPN)
-					}
-				}
+				tlsOptions = parseInboundTLS(streamSettings.TLSSettings, path+".streamSettings.tlsSettings", inbound.Tag, report)
+			default:
+				report.Warn(path+".streamSettings.security", inbound.Tag, "unsupported security ", security, " is not migrated")
 			}
 		}
 	}
@@ -94,12 +107,37 @@ func migrateInbound(inboundConfig v4json.InboundDetourConfig) (option.Inbound, e
 	}
 	rawConfig, err := inboundConfigLoader.LoadWithID(settingsString, inboundConfig.Protocol)
 	if err != nil {
-		return option.Inbound{}, err
+		return nil, err
 	}
 	proxySettings, err := rawConfig.(cfgcommon.Buildable).Build()
 	if err != nil {
-		return option.Inbound{}, err
+		return nil, err
+	}
+	inbound, err = newInbound(inbound.Tag, proxySettings, listenOptions, tproxyName, tlsOptions, transportOptions)
+	if err != nil {
+		return nil, err
+	}
+	switch inbound.Type {
+	case C.TypeVMess, C.TypeVLESS, C.TypeTrojan:
+	default:
+		if transportOptions.Type != "" {
+			report.Warn(path+".streamSettings.network", inbound.Tag, "transport is not supported by ", inbound.Type, " inbound")
+		}
+		if tlsOptions.Enabled && inbound.Type != C.TypeHTTP {
+			report.Warn(path+".streamSettings.security", inbound.Tag, "TLS is not supported by ", inbound.Type, " inbound")
+		}
 	}
+	if portRange := inboundConfig.PortRange; portRange != nil {
+		ports := allocatePorts(inboundConfig.Allocation, listPortRange(portRange.From, portRange.To), maxPorts, path+".allocate", inbound.Tag, report)
+		return migratePorts(inbound, ports, F.ToString(portRange.From, "-", portRange.To), inboundTags, maxPorts, path+".port", report), nil
+	}
+	return []option.Inbound{inbound}, nil
+}
+
+// newInbound converts a built v2ray inbound proxy config, shared by the v4 and v5 formats.
+func newInbound(tag string, proxySettings any, listenOptions option.ListenOptions, tproxyName string, tlsOptions option.InboundTLSOptions, transportOptions option.V2RayTransportOptions) (option.Inbound, error) {
+	var inbound option.Inbound
+	inbound.Tag = tag
 	switch proxyType := proxySettings.(type) {
 	case *dokodemo.Config:
 		if proxyType.FollowRedirect || tproxyName == "redirect" {
@@ -121,14 +159,14 @@ func migrateInbound(inboundConfig v4json.InboundDetourConfig) (option.Inbound, e
 	case *http.ServerConfig:
 		inbound.Type = C.TypeHTTP
 		inbound.HTTPOptions.ListenOptions = listenOptions
+		if tlsOptions.Enabled {
+			inbound.HTTPOptions.TLS = &tlsOptions
+		}
 		for username, password := range proxyType.Accounts {
 			inbound.HTTPOptions.Users = append(inbound.HTTPOptions.Users, auth.User{
 				Username: username,
 				Password: password,
 			})
-			if tlsOptions.Enabled {
-				inbound.HTTPOptions.TLS = &tlsOptions
-			}
 		}
 	case *socks.ServerConfig:
 		inbound.Type = C.TypeSocks
@@ -152,10 +190,14 @@ func migrateInbound(inboundConfig v4json.InboundDetourConfig) (option.Inbound, e
 			switch shadowsocksAccountType.CipherType {
 			case shadowsocks.CipherType_AES_128_GCM:
 				inbound.ShadowsocksOptions.Method = "aes-128-gcm"
+			case shadowsocks.CipherType_AES_256_GCM:
+				inbound.ShadowsocksOptions.Method = "aes-256-gcm"
 			case shadowsocks.CipherType_CHACHA20_POLY1305:
 				inbound.ShadowsocksOptions.Method = "chacha20-ietf-poly1305"
-			default:
+			case shadowsocks.CipherType_NONE:
 				inbound.ShadowsocksOptions.Method = "none"
+			default:
+				return option.Inbound{}, v2box.NewPathError("settings.method", "unsupported shadowsocks cipher: ", shadowsocksAccountType.CipherType)
 			}
 			inbound.ShadowsocksOptions.Password = shadowsocksAccountType.Password
 		}
@@ -228,7 +270,7 @@ func migrateInbound(inboundConfig v4json.InboundDetourConfig) (option.Inbound, e
 			}
 		}
 	default:
-		return option.Inbound{}, E.New("unsupported inbound type ", reflect.TypeOf(proxyType))
+		return option.Inbound{}, v2box.NewPathError("protocol", "unsupported inbound type ", reflect.TypeOf(proxyType))
 	}
 	return inbound, nil
 }
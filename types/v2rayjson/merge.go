@@ -0,0 +1,17 @@
+package v2rayjson
+
+import (
+	"github.com/sagernet/v2box"
+
+	"github.com/v2fly/v2ray-core/v5/infra/conf/merge"
+)
+
+// Merge merges files with the V2Ray rules: objects are merged recursively,
+// array elements with the same tag are merged and sorted by _priority.
+func Merge(files []v2box.ConfigFile) ([]byte, error) {
+	contents := make([][]byte, 0, len(files))
+	for _, file := range files {
+		contents = append(contents, file.Content)
+	}
+	return merge.JSONs(contents)
+}
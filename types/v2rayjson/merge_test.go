@@ -0,0 +1,49 @@
+package v2rayjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sagernet/v2box"
+)
+
+func TestMerge(t *testing.T) {
+	content, err := Merge([]v2box.ConfigFile{
+		{Name: "1.json", Content: []byte(`{
+			"log": {"loglevel": "debug"},
+			"outbounds": [{"tag": "a", "protocol": "vmess"}, {"tag": "b"}]
+		}`)},
+		{Name: "2.json", Content: []byte(`{
+			"log": {"loglevel": "error"},
+			"outbounds": [{"tag": "b", "protocol": "freedom"}, {"tag": "c", "_priority": -1}]
+		}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config struct {
+		Log struct {
+			LogLevel string `json:"loglevel"`
+		} `json:"log"`
+		Outbounds []struct {
+			Tag      string `json:"tag"`
+			Protocol string `json:"protocol"`
+		} `json:"outbounds"`
+	}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// later files override values, arrays are appended and elements with the same tag are merged.
+	if config.Log.LogLevel != "error" {
+		t.Errorf("expected log level error, got %s", config.Log.LogLevel)
+	}
+	var outbounds []string
+	for _, outbound := range config.Outbounds {
+		outbounds = append(outbounds, outbound.Tag+":"+outbound.Protocol)
+	}
+	if expected := []string{"c:", "a:vmess", "b:freedom"}; !reflect.DeepEqual(outbounds, expected) {
+		t.Errorf("expected outbounds %v, got %v", expected, outbounds)
+	}
+}
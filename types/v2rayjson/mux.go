@@ -0,0 +1,20 @@
+package v2rayjson
+
+import (
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/v2box"
+
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/muxcfg"
+)
+
+var errMux = E.New("mux is not supported, as sing-box multiplex is not compatible with mux.cool servers, connections are not multiplexed")
+
+// migrateMultiplex reports enabled mux settings of the outbound as dropped.
+// Multiplex is left disabled, since enabling it would break connections to mux.cool servers.
+func migrateMultiplex(outbound *option.Outbound, muxSettings *muxcfg.MuxConfig, path string, report *v2box.Report) {
+	if muxSettings == nil || !muxSettings.Enabled || muxSettings.Concurrency < 0 {
+		return
+	}
+	report.Drop(path, outbound.Tag, errMux)
+}
@@ -10,11 +10,12 @@ import (
 	"github.com/sagernet/sing-dns"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
 
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
-	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/loader"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/socketcfg"
 	v4json "github.com/v2fly/v2ray-core/v5/infra/conf/v4"
 	"github.com/v2fly/v2ray-core/v5/proxy/blackhole"
 	proxy_dns "github.com/v2fly/v2ray-core/v5/proxy/dns"
@@ -24,85 +25,107 @@ import (
 	"github.com/v2fly/v2ray-core/v5/proxy/shadowsocks"
 	"github.com/v2fly/v2ray-core/v5/proxy/socks"
 	"github.com/v2fly/v2ray-core/v5/proxy/trojan"
-	"github.com/v2fly/v2ray-core/v5/proxy/vless"
 	vless_outbound "github.com/v2fly/v2ray-core/v5/proxy/vless/outbound"
-	"github.com/v2fly/v2ray-core/v5/proxy/vmess"
 	vmess_outbound "github.com/v2fly/v2ray-core/v5/proxy/vmess/outbound"
 )
 
 //go:linkname outboundConfigLoader github.com/v2fly/v2ray-core/v5/infra/conf/v4.outboundConfigLoader
 var outboundConfigLoader *loader.JSONConfigLoader
 
-func migrateOutbound(outboundConfig v4json.OutboundDetourConfig, dnsRule *option.DefaultDNSRule) (option.Outbound, error) {
+func migrateOutbound(outboundConfig v4json.OutboundDetourConfig, outboundTags v2box.TagSet, path string, report *v2box.Report, dnsRule *option.DefaultDNSRule) ([]option.Outbound, error) {
 	var outbound option.Outbound
 	outbound.Tag = outboundConfig.Tag
 
 	var dialOptions option.DialerOptions
 	var tlsOptions option.OutboundTLSOptions
 	var transportOptions option.V2RayTransportOptions
-	// var multiplexOptions option.MultiplexOptions
 	var err error
 
 	if streamSettings := outboundConfig.StreamSetting; streamSettings != nil {
 		if socketSettings := streamSettings.SocketSettings; socketSettings != nil {
-			if socketSettings.Mark > 0 {
-				dialOptions.RoutingMark = int(socketSettings.Mark)
-			}
-			if socketSettings.TFO != nil {
-				dialOptions.TCPFastOpen = *socketSettings.TFO
-			}
-			dialOptions.BindInterface = socketSettings.BindToDevice
+			parseSocketOptions(socketSettings, &dialOptions, path+".streamSettings.sockopt", outbound.Tag, report)
 		}
 		transportOptions, err = parseTransport(streamSettings)
 		if err != nil {
-			return option.Outbound{}, err
+			return nil, err
 		}
 		if security := streamSettings.Security; security != "" {
 			switch security {
+			case "none":
 			case "tls":
-				tlsOptions.Enabled = true
-				if tlsSettings := streamSettings.TLSSettings; tlsSettings != nil {
-					tlsOptions.Insecure = tlsSettings.Insecure
-					tlsOptions.ServerName = tlsSettings.ServerName
-					for _, certConfig := range tlsSettings.Certs {
-						if certConfig.Usage != "" && certConfig.Usage != "encipherment" {
-							continue
-						}
-						if len(certConfig.CertStr) > 0 {
-							tlsOptions.Certificate = strings.Join(certConfig.CertStr, "\n")
-						}
-						tlsOptions.CertificatePath = certConfig.CertFile
-					}
-					if tlsSettings.ALPN != nil && tlsSettings.ALPN.Len() > 0 {
-						tlsOptions.ALPN = []string(*tlsSettings.ALPN)
-					}
-				}
+				tlsOptions = parseOutboundTLS(streamSettings.TLSSettings, path+".streamSettings.tlsSettings", outbound.Tag, report)
+			default:
+				report.Warn(path+".streamSettings.security", outbound.Tag, "unsupported security ", security, " is not migrated")
 			}
 		}
 	}
-	/*if muxSettings := outboundConfig.MuxSettings; muxSettings != nil {
-		multiplexOptions.Enabled = true
-		multiplexOptions.MaxConnections = int(outboundConfig.MuxSettings.Concurrency)
-	}*/
+	if proxySettings := outboundConfig.ProxySettings; proxySettings != nil && proxySettings.Tag != "" {
+		dialOptions.Detour = proxySettings.Tag
+		if !proxySettings.TransportLayerProxy && (transportOptions.Type != "" || tlsOptions.Enabled) {
+			// V2Ray sends the proxy protocol through the upstream outbound without stream settings
+			transportOptions = option.V2RayTransportOptions{}
+			tlsOptions = option.OutboundTLSOptions{}
+			report.Info(path+".streamSettings", outbound.Tag, "stream settings are ignored by proxy chaining without transportLayer")
+		}
+		// sing-box sends the server domain of a chained outbound to the upstream proxy, which resolves it
+		dnsRule = nil
+	}
+	if outboundConfig.SendThrough != nil {
+		err = parseSendThrough(outboundConfig.SendThrough, &dialOptions)
+		if err != nil {
+			return nil, v2box.WrapPathError("sendThrough", err)
+		}
+	}
+	dialOptions.DomainStrategy = parseOutboundStrategy(outboundConfig.DomainStrategy)
+	if dialOptions.Detour != "" && dialOptions.DomainStrategy != option.DomainStrategy(dns.DomainStrategyAsIS) {
+		report.Warn(path+".domainStrategy", outbound.Tag, "the server domain is resolved by the DNS rules before it is sent through ", dialOptions.Detour)
+	}
 	settingsString := []byte("{}")
 	if outboundConfig.Settings != nil {
 		settingsString = *outboundConfig.Settings
 	}
 	rawConfig, err := outboundConfigLoader.LoadWithID(settingsString, outboundConfig.Protocol)
 	if err != nil {
-		return option.Outbound{}, err
+		return nil, err
 	}
 	proxySettings, err := rawConfig.(cfgcommon.Buildable).Build()
 	if err != nil {
-		return option.Outbound{}, err
+		return nil, err
+	}
+	outbound, servers, err := newOutbound(outbound.Tag, proxySettings, tlsOptions, transportOptions)
+	if err != nil {
+		return nil, err
+	}
+	switch outbound.Type {
+	case C.TypeVMess, C.TypeVLESS, C.TypeTrojan:
+	default:
+		if transportOptions.Type != "" {
+			report.Warn(path+".streamSettings.network", outbound.Tag, "transport is not supported by ", outbound.Type, " outbound")
+		}
+		if tlsOptions.Enabled && outbound.Type != C.TypeHTTP {
+			report.Warn(path+".streamSettings.security", outbound.Tag, "TLS is not supported by ", outbound.Type, " outbound")
+		}
 	}
+	if dialOptions != (option.DialerOptions{}) && !setDialerOptions(&outbound, dialOptions) {
+		report.Warn(path, outbound.Tag, "dialer options are not supported by ", outbound.Type, " outbound")
+	}
+	migrateMultiplex(&outbound, outboundConfig.MuxSettings, path+".mux", report)
+	return migrateEndpoints(outbound, servers, outboundTags, path, report, dnsRule)
+}
+
+// newOutbound converts a built v2ray outbound proxy config, shared by the v4 and v5 formats.
+// The server endpoints are returned to be set by migrateEndpoints.
+func newOutbound(tag string, proxySettings any, tlsOptions option.OutboundTLSOptions, transportOptions option.V2RayTransportOptions) (option.Outbound, []*protocol.ServerEndpoint, error) {
+	var outbound option.Outbound
+	var servers []*protocol.ServerEndpoint
+	outbound.Tag = tag
 	switch proxyType := proxySettings.(type) {
 	case *blackhole.Config:
 		outbound.Type = C.TypeBlock
 	case *proxy_dns.Config:
 		outbound.Type = C.TypeDNS
 	case *loopback.Config:
-		return option.Outbound{}, E.New("loopback is not supported, please rewrite your config using listenOptions.detour")
+		return option.Outbound{}, nil, v2box.NewPathError("protocol", "loopback is not supported, please rewrite your config using listenOptions.detour")
 	case *freedom.Config:
 		outbound.Type = C.TypeDirect
 		if destinationOverride := proxyType.DestinationOverride; destinationOverride != nil {
@@ -125,21 +148,7 @@ func migrateOutbound(outboundConfig v4json.OutboundDetourConfig, dnsRule *option
 		if tlsOptions.Enabled {
 			outbound.HTTPOptions.TLS = &tlsOptions
 		}
-		serverAddress, users := parseServerAddress(proxyType.Server)
-		addServerToDNSOptions(serverAddress, dnsRule)
-		outbound.HTTPOptions.Server = serverAddress.AddrString()
-		outbound.HTTPOptions.ServerPort = serverAddress.Port
-		for _, user := range users {
-			account, err := serial.GetInstanceOf(user.Account)
-			if err != nil {
-				return option.Outbound{}, E.Cause(err, "get instance of ", user.Account.TypeUrl)
-			}
-			switch accountType := account.(type) {
-			case *http.Account:
-				outbound.HTTPOptions.Username = accountType.Username
-				outbound.HTTPOptions.Password = accountType.Password
-			}
-		}
+		servers = proxyType.Server
 	case *socks.ClientConfig:
 		outbound.Type = C.TypeSocks
 		switch proxyType.Version {
@@ -148,50 +157,10 @@ func migrateOutbound(outboundConfig v4json.OutboundDetourConfig, dnsRule *option
 		case socks.Version_SOCKS4A:
 			outbound.SocksOptions.Version = "4a"
 		}
-		serverAddress, users := parseServerAddress(proxyType.Server)
-		addServerToDNSOptions(serverAddress, dnsRule)
-		outbound.SocksOptions.Server = serverAddress.AddrString()
-		outbound.SocksOptions.ServerPort = serverAddress.Port
-		for _, user := range users {
-			account, err := serial.GetInstanceOf(user.Account)
-			if err != nil {
-				return option.Outbound{}, E.Cause(err, "get instance of ", user.Account.TypeUrl)
-			}
-			switch accountType := account.(type) {
-			case *socks.Account:
-				outbound.SocksOptions.Username = accountType.Username
-				outbound.SocksOptions.Password = accountType.Password
-			}
-		}
+		servers = proxyType.Server
 	case *shadowsocks.ClientConfig:
 		outbound.Type = C.TypeShadowsocks
-		serverAddress, users := parseServerAddress(proxyType.Server)
-		addServerToDNSOptions(serverAddress, dnsRule)
-		outbound.ShadowsocksOptions.Server = serverAddress.AddrString()
-		outbound.ShadowsocksOptions.ServerPort = serverAddress.Port
-		for _, user := range users {
-			account, err := serial.GetInstanceOf(user.Account)
-			if err != nil {
-				return option.Outbound{}, E.Cause(err, "get instance of ", user.Account.TypeUrl)
-			}
-			switch accountType := account.(type) {
-			case *shadowsocks.Account:
-				var method string
-				switch accountType.CipherType {
-				case shadowsocks.CipherType_AES_128_GCM:
-					method = "aes-128-gcm"
-				case shadowsocks.CipherType_AES_256_GCM:
-					method = "aes-256-gcm"
-				case shadowsocks.CipherType_CHACHA20_POLY1305:
-					method = "chacha20-ietf-poly1305"
-				default:
-					method = "none"
-				}
-				outbound.ShadowsocksOptions.Method = method
-				outbound.ShadowsocksOptions.Password = accountType.Password
-			}
-		}
-
+		servers = proxyType.Server
 	case *trojan.ClientConfig:
 		outbound.Type = C.TypeTrojan
 		if tlsOptions.Enabled {
@@ -200,20 +169,7 @@ func migrateOutbound(outboundConfig v4json.OutboundDetourConfig, dnsRule *option
 		if transportOptions.Type != "" {
 			outbound.TrojanOptions.Transport = &transportOptions
 		}
-		serverAddress, users := parseServerAddress(proxyType.Server)
-		addServerToDNSOptions(serverAddress, dnsRule)
-		outbound.TrojanOptions.Server = serverAddress.AddrString()
-		outbound.TrojanOptions.ServerPort = serverAddress.Port
-		for _, user := range users {
-			account, err := serial.GetInstanceOf(user.Account)
-			if err != nil {
-				return option.Outbound{}, E.Cause(err, "get instance of ", user.Account.TypeUrl)
-			}
-			switch accountType := account.(type) {
-			case *trojan.Account:
-				outbound.TrojanOptions.Password = accountType.Password
-			}
-		}
+		servers = proxyType.Server
 	case *vmess_outbound.Config:
 		outbound.Type = C.TypeVMess
 		if tlsOptions.Enabled {
@@ -222,36 +178,7 @@ func migrateOutbound(outboundConfig v4json.OutboundDetourConfig, dnsRule *option
 		if transportOptions.Type != "" {
 			outbound.VMessOptions.Transport = &transportOptions
 		}
-		serverAddress, users := parseServerAddress(proxyType.Receiver)
-		addServerToDNSOptions(serverAddress, dnsRule)
-		outbound.VMessOptions.Server = serverAddress.AddrString()
-		outbound.VMessOptions.ServerPort = serverAddress.Port
-		for _, user := range users {
-			account, err := serial.GetInstanceOf(user.Account)
-			if err != nil {
-				return option.Outbound{}, E.Cause(err, "get instance of ", user.Account.TypeUrl)
-			}
-			switch accountType := account.(type) {
-			case *vmess.Account:
-				var security string
-				switch accountType.SecuritySettings.Type {
-				case protocol.SecurityType_AES128_GCM:
-					security = "aes-128-gcm"
-				case protocol.SecurityType_CHACHA20_POLY1305:
-					security = "chacha20-poly1305"
-				case protocol.SecurityType_NONE:
-					security = "none"
-				case protocol.SecurityType_ZERO:
-					security = "zero"
-				}
-				outbound.VMessOptions.UUID = accountType.Id
-				outbound.VMessOptions.Security = security
-				outbound.VMessOptions.AlterId = int(accountType.AlterId)
-				if strings.Contains(accountType.TestsEnabled, "AuthenticatedLength") {
-					outbound.VMessOptions.AuthenticatedLength = true
-				}
-			}
-		}
+		servers = proxyType.Receiver
 	case *vless_outbound.Config:
 		outbound.Type = C.TypeVLESS
 		if tlsOptions.Enabled {
@@ -260,29 +187,99 @@ func migrateOutbound(outboundConfig v4json.OutboundDetourConfig, dnsRule *option
 		if transportOptions.Type != "" {
 			outbound.VLESSOptions.Transport = &transportOptions
 		}
-		serverAddress, users := parseServerAddress(proxyType.Vnext)
-		addServerToDNSOptions(serverAddress, dnsRule)
-		outbound.VLESSOptions.Server = serverAddress.AddrString()
-		outbound.VLESSOptions.ServerPort = serverAddress.Port
-		for _, user := range users {
-			account, err := serial.GetInstanceOf(user.Account)
-			if err != nil {
-				return option.Outbound{}, E.Cause(err, "get instance of ", user.Account.TypeUrl)
-			}
-			switch accountType := account.(type) {
-			case *vless.Account:
-				outbound.VLESSOptions.UUID = accountType.Id
-				outbound.VLESSOptions.Flow = accountType.Flow
-			}
-		}
+		servers = proxyType.Vnext
 	default:
-		return option.Outbound{}, E.New("unknown outbound type: ", reflect.TypeOf(proxyType))
+		return option.Outbound{}, nil, v2box.NewPathError("protocol", "unknown outbound type: ", reflect.TypeOf(proxyType))
+	}
+	return outbound, servers, nil
+}
+
+// setDialerOptions attaches dialer options to outbound types dialing their own connections,
+// keeping the domain strategy of freedom outbounds unless overridden.
+func setDialerOptions(outbound *option.Outbound, dialerOptions option.DialerOptions) bool {
+	var outboundDialer *option.DialerOptions
+	switch outbound.Type {
+	case C.TypeDirect:
+		outboundDialer = &outbound.DirectOptions.DialerOptions
+	case C.TypeHTTP:
+		outboundDialer = &outbound.HTTPOptions.DialerOptions
+	case C.TypeSocks:
+		outboundDialer = &outbound.SocksOptions.DialerOptions
+	case C.TypeShadowsocks:
+		outboundDialer = &outbound.ShadowsocksOptions.DialerOptions
+	case C.TypeVMess:
+		outboundDialer = &outbound.VMessOptions.DialerOptions
+	case C.TypeTrojan:
+		outboundDialer = &outbound.TrojanOptions.DialerOptions
+	case C.TypeVLESS:
+		outboundDialer = &outbound.VLESSOptions.DialerOptions
+	default:
+		return false
+	}
+	if dialerOptions.DomainStrategy == option.DomainStrategy(dns.DomainStrategyAsIS) {
+		dialerOptions.DomainStrategy = outboundDialer.DomainStrategy
+	}
+	*outboundDialer = dialerOptions
+	return true
+}
+
+// parseSocketOptions converts the socket options of an outbound into dialer options,
+// sing-box has no equivalent for keepalive, tproxy and buffer size options.
+func parseSocketOptions(socketSettings *socketcfg.SocketConfig, dialOptions *option.DialerOptions, path string, tag string, report *v2box.Report) {
+	if socketSettings.Mark > 0 {
+		dialOptions.RoutingMark = int(socketSettings.Mark)
+	}
+	if socketSettings.TFO != nil {
+		dialOptions.TCPFastOpen = *socketSettings.TFO
+	}
+	dialOptions.BindInterface = socketSettings.BindToDevice
+	var unsupported []string
+	if socketSettings.TCPKeepAliveInterval != 0 {
+		unsupported = append(unsupported, "tcpKeepAliveInterval")
+	}
+	if socketSettings.TCPKeepAliveIdle != 0 {
+		unsupported = append(unsupported, "tcpKeepAliveIdle")
+	}
+	if socketSettings.TProxy != "" && strings.ToLower(socketSettings.TProxy) != "off" {
+		unsupported = append(unsupported, "tproxy")
+	}
+	if socketSettings.RxBufSize != 0 || socketSettings.TxBufSize != 0 || socketSettings.ForceBufSize {
+		unsupported = append(unsupported, "rxBufSize", "txBufSize", "forceBufSize")
+	}
+	if len(unsupported) > 0 {
+		report.Warn(path, tag, "socket options ", strings.Join(unsupported, ", "), " are not migrated")
+	}
+}
+
+// parseSendThrough binds outgoing connections to the local address like V2Ray, which does not accept domains.
+func parseSendThrough(address *cfgcommon.Address, dialOptions *option.DialerOptions) error {
+	if address.Family().IsDomain() {
+		return E.New("unable to send through: ", address.String())
+	}
+	bindAddress := M.AddrFromIP(address.IP())
+	if bindAddress.Is4() {
+		dialOptions.Inet4BindAddress = option.NewListenAddress(bindAddress)
+	} else {
+		dialOptions.Inet6BindAddress = option.NewListenAddress(bindAddress)
+	}
+	return nil
+}
+
+// parseOutboundStrategy converts the domain strategy of an outbound, UseIP prefers IPv4 like freedom outbounds.
+func parseOutboundStrategy(domainStrategy string) option.DomainStrategy {
+	switch strings.ToLower(domainStrategy) {
+	case "useip", "use_ip", "use-ip":
+		return option.DomainStrategy(dns.DomainStrategyPreferIPv4)
+	case "useip4", "useipv4", "use_ip4", "use_ipv4", "use_ip_v4", "use-ip4", "use-ipv4", "use-ip-v4":
+		return option.DomainStrategy(dns.DomainStrategyUseIPv4)
+	case "useip6", "useipv6", "use_ip6", "use_ipv6", "use_ip_v6", "use-ip6", "use-ipv6", "use-ip-v6":
+		return option.DomainStrategy(dns.DomainStrategyUseIPv6)
 	}
-	return outbound, nil
+	return option.DomainStrategy(dns.DomainStrategyAsIS)
 }
 
 func addServerToDNSOptions(address M.Socksaddr, dnsRule *option.DefaultDNSRule) {
-	if address.IsFqdn() {
+	if dnsRule != nil && address.IsFqdn() {
 		dnsRule.Domain = append(dnsRule.Domain, address.Fqdn)
 	}
 }
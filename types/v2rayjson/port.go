@@ -0,0 +1,120 @@
+package v2rayjson
+
+import (
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/v2box"
+
+	v4json "github.com/v2fly/v2ray-core/v5/infra/conf/v4"
+)
+
+// migratePorts copies the inbound for each of the ports, with the port suffixed to the tag.
+// sing-box inbounds listen on a single port, so more than maxPorts ports are reduced to the first port,
+// and the other ports have to be redirected to it by the firewall, redirect rules are not generated.
+func migratePorts(inbound option.Inbound, ports []uint16, portDescription string, inboundTags v2box.TagSet, maxPorts int, path string, report *v2box.Report) []option.Inbound {
+	if len(ports) == 0 {
+		return []option.Inbound{inbound}
+	}
+	setListenPort(&inbound, ports[0])
+	if len(ports) == 1 {
+		return []option.Inbound{inbound}
+	}
+	if len(ports) > maxPorts {
+		report.Warn(path, inbound.Tag, "port ", portDescription, " exceeds the limit of ", maxPorts, " ports and is reduced to port ", ports[0], ", redirect the other ports to it by firewall rules, which are not generated")
+		return []option.Inbound{inbound}
+	}
+	inbounds := make([]option.Inbound, 0, len(ports))
+	for _, port := range ports {
+		portInbound := inbound
+		if inbound.Tag != "" {
+			portInbound.Tag = inboundTags.New(F.ToString(inbound.Tag, "-", port))
+		}
+		setListenPort(&portInbound, port)
+		inbounds = append(inbounds, portInbound)
+	}
+	report.Info(path, inbound.Tag, "port ", portDescription, " is migrated to ", len(inbounds), " inbounds")
+	return inbounds
+}
+
+func listPortRange(from uint32, to uint32) []uint16 {
+	var ports []uint16
+	for port := from; port <= to && port <= 65535; port++ {
+		ports = append(ports, uint16(port))
+	}
+	return ports
+}
+
+// expandInboundTags replaces the tags of inbounds expanded by port with the tags of their copies.
+func expandInboundTags(tags []string, inboundTags map[string][]string) []string {
+	var expandedTags []string
+	for _, tag := range tags {
+		if portTags, loaded := inboundTags[tag]; loaded {
+			expandedTags = append(expandedTags, portTags...)
+		} else {
+			expandedTags = append(expandedTags, tag)
+		}
+	}
+	return expandedTags
+}
+
+// allocatePorts converts a random allocation into the fixed ports to listen on.
+// V2Ray listens on concurrency random ports of the range and reallocates them every refresh minutes,
+// sing-box inbounds are never reallocated, so all ports of the range are listened on when they fit in maxPorts,
+// or the first concurrency ports, at most maxPorts, otherwise.
+func allocatePorts(allocation *v4json.InboundDetourAllocationConfig, ports []uint16, maxPorts int, path string, tag string, report *v2box.Report) []uint16 {
+	if allocation == nil || len(ports) == 0 {
+		return ports
+	}
+	switch strings.ToLower(allocation.Strategy) {
+	case "", "always":
+		return ports
+	case "random":
+	default:
+		report.Warn(path, tag, "allocation strategy ", allocation.Strategy, " is not migrated")
+		return ports
+	}
+	concurrency := 3
+	if allocation.Concurrency != nil && *allocation.Concurrency > 0 {
+		concurrency = int(*allocation.Concurrency)
+	}
+	refresh := 5
+	if allocation.RefreshMin != nil && *allocation.RefreshMin > 0 {
+		refresh = int(*allocation.RefreshMin)
+	}
+	if len(ports) <= maxPorts || concurrency >= len(ports) {
+		report.Warn(path, tag, "random allocation of ", concurrency, " ports refreshed every ", refresh, " minutes is migrated to fixed listeners on all ", len(ports), " ports")
+		return ports
+	}
+	allocated := concurrency
+	if allocated > maxPorts {
+		allocated = maxPorts
+	}
+	report.Warn(path, tag, "random allocation of ", concurrency, " ports refreshed every ", refresh, " minutes is migrated to fixed listeners on the first ", allocated, " ports")
+	return ports[:allocated]
+}
+
+func setListenPort(inbound *option.Inbound, port uint16) {
+	switch inbound.Type {
+	case C.TypeDirect:
+		inbound.DirectOptions.ListenPort = port
+	case C.TypeRedirect:
+		inbound.RedirectOptions.ListenPort = port
+	case C.TypeTProxy:
+		inbound.TProxyOptions.ListenPort = port
+	case C.TypeHTTP:
+		inbound.HTTPOptions.ListenPort = port
+	case C.TypeSocks:
+		inbound.SocksOptions.ListenPort = port
+	case C.TypeShadowsocks:
+		inbound.ShadowsocksOptions.ListenPort = port
+	case C.TypeVMess:
+		inbound.VMessOptions.ListenPort = port
+	case C.TypeVLESS:
+		inbound.VLESSOptions.ListenPort = port
+	case C.TypeTrojan:
+		inbound.TrojanOptions.ListenPort = port
+	}
+}
@@ -0,0 +1,51 @@
+package v2rayjson
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sagernet/v2box"
+)
+
+func TestMigratePortsRuleTags(t *testing.T) {
+	options, _ := migrateTest(t, `{
+		"inbounds": [
+			{"protocol": "socks", "tag": "in", "port": "1080-1082"},
+			{"protocol": "socks", "tag": "in-1081", "port": 2080}
+		],
+		"outbounds": [{"protocol": "freedom", "tag": "direct"}],
+		"routing": {"rules": [{"type": "field", "inboundTag": ["in"], "outboundTag": "direct"}]}
+	}`)
+	var tags []string
+	var ports []uint16
+	for _, inbound := range options.Inbounds {
+		tags = append(tags, inbound.Tag)
+		ports = append(ports, inbound.SocksOptions.ListenPort)
+	}
+	if expected := []string{"in-1080", "in-1081-2", "in-1082", "in-1081"}; !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected inbounds %v, got %v", expected, tags)
+	}
+	if expected := []uint16{1080, 1081, 1082, 2080}; !reflect.DeepEqual(ports, expected) {
+		t.Errorf("expected ports %v, got %v", expected, ports)
+	}
+	if expected := []string{"in-1080", "in-1081-2", "in-1082"}; !reflect.DeepEqual([]string(options.Route.Rules[0].DefaultOptions.Inbound), expected) {
+		t.Errorf("expected rule inbounds %v, got %v", expected, options.Route.Rules[0].DefaultOptions.Inbound)
+	}
+}
+
+func TestMigratePortsLimit(t *testing.T) {
+	options, report := migrateTest(t, `{
+		"inbounds": [{"protocol": "socks", "tag": "in", "port": "10000-10100"}],
+		"routing": {"rules": [{"type": "field", "inboundTag": ["in"], "outboundTag": "direct"}]},
+		"outbounds": [{"protocol": "freedom", "tag": "direct"}]
+	}`)
+	if len(options.Inbounds) != 1 || options.Inbounds[0].Tag != "in" || options.Inbounds[0].SocksOptions.ListenPort != 10000 {
+		t.Fatalf("expected inbound in on port 10000, got %+v", options.Inbounds)
+	}
+	if expected := []string{"in"}; !reflect.DeepEqual([]string(options.Route.Rules[0].DefaultOptions.Inbound), expected) {
+		t.Errorf("expected rule inbounds %v, got %v", expected, options.Route.Rules[0].DefaultOptions.Inbound)
+	}
+	if !hasReport(report, v2box.SeverityWarning, "inbounds[0].port") {
+		t.Error("expected a warning for inbounds[0].port")
+	}
+}
@@ -6,8 +6,10 @@ import (
 
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/format"
+	"github.com/sagernet/v2box"
 
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	conf_rule "github.com/v2fly/v2ray-core/v5/infra/conf/rule"
@@ -28,18 +30,15 @@ type RawFieldRule struct {
 	Attributes string                  `json:"attrs"`
 }
 
-func migrateRule(ruleMessage json.RawMessage) (option.Rule, error) {
+func migrateRule(ruleMessage json.RawMessage, balancerTags []string, inboundTags map[string][]string) (option.Rule, error) {
 	var rule option.DefaultRule
 	var rawRule conf_rule.RouterRule
 	err := json.Unmarshal(ruleMessage, &rawRule)
 	if err != nil {
 		return option.Rule{}, err
 	}
-	if rawRule.BalancerTag != "" {
-		return option.Rule{}, E.New("balancer rule is not supported")
-	}
 	if rawRule.Type != "field" {
-		return option.Rule{}, E.New("unknown router rule type: ", rawRule.Type)
+		return option.Rule{}, v2box.NewPathError("type", "unknown router rule type: ", rawRule.Type)
 	}
 	var field RawFieldRule
 	err = json.Unmarshal(ruleMessage, &field)
@@ -47,28 +46,34 @@ func migrateRule(ruleMessage json.RawMessage) (option.Rule, error) {
 		return option.Rule{}, err
 	}
 	rule.Outbound = field.OutboundTag
-	for _, domain := range field.Domain {
+	if rule.Outbound == "" && rawRule.BalancerTag != "" {
+		if !common.Contains(balancerTags, rawRule.BalancerTag) {
+			return option.Rule{}, v2box.NewPathError("balancerTag", "balancer ", rawRule.BalancerTag, " is not migrated")
+		}
+		rule.Outbound = rawRule.BalancerTag
+	}
+	for i, domain := range field.Domain {
 		err = parseDomain(domain, &rule)
 		if err != nil {
-			return option.Rule{}, err
+			return option.Rule{}, v2box.WrapPathError(v2box.IndexPath("domain", i), err)
 		}
 	}
-	for _, domain := range field.Domains {
+	for i, domain := range field.Domains {
 		err = parseDomain(domain, &rule)
 		if err != nil {
-			return option.Rule{}, err
+			return option.Rule{}, v2box.WrapPathError(v2box.IndexPath("domains", i), err)
 		}
 	}
-	for _, address := range field.IP {
+	for i, address := range field.IP {
 		err = parseAddress(address, false, &rule)
 		if err != nil {
-			return option.Rule{}, err
+			return option.Rule{}, v2box.WrapPathError(v2box.IndexPath("ip", i), err)
 		}
 	}
-	for _, address := range field.SourceIP {
+	for i, address := range field.SourceIP {
 		err = parseAddress(address, true, &rule)
 		if err != nil {
-			return option.Rule{}, err
+			return option.Rule{}, v2box.WrapPathError(v2box.IndexPath("source", i), err)
 		}
 	}
 	if field.Port != nil {
@@ -91,10 +96,10 @@ func migrateRule(ruleMessage json.RawMessage) (option.Rule, error) {
 	}
 	rule.Network = parseNetworkList(field.Network)
 	rule.AuthUser = field.User
-	rule.Inbound = field.InboundTag
+	rule.Inbound = expandInboundTags(field.InboundTag, inboundTags)
 	rule.Protocol = field.Protocols
 	if field.Attributes != "" {
-		return option.Rule{}, E.New("attributes rule is not supported")
+		return option.Rule{}, v2box.NewPathError("attrs", "attributes rule is not supported")
 	}
 	return option.Rule{
 		Type:           C.RuleTypeDefault,
@@ -111,7 +116,7 @@ func parseDomain(domain string, rule *option.DefaultRule) error {
 		return nil
 	} else if strings.HasPrefix(domain, "regexp:") {
 		domain = domain[7:]
-		rule.DomainRegex = append(rule.Domain, domain)
+		rule.DomainRegex = append(rule.DomainRegex, domain)
 		return nil
 	} else if strings.HasPrefix(domain, "domain:") {
 		domain = domain[7:]
@@ -0,0 +1,109 @@
+package v2rayjson
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/v2box"
+
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/tlscfg"
+)
+
+// parseInboundTLS converts the TLS settings of an inbound.
+// sing-box serves a single certificate to every server name and does not verify clients,
+// so only the first encipherment certificate is kept and certificate authorities are reported.
+func parseInboundTLS(tlsSettings *tlscfg.TLSConfig, path string, tag string, report *v2box.Report) option.InboundTLSOptions {
+	tlsOptions := option.InboundTLSOptions{Enabled: true}
+	if tlsSettings == nil {
+		return tlsOptions
+	}
+	tlsOptions.ServerName = tlsSettings.ServerName
+	if tlsSettings.ALPN != nil && tlsSettings.ALPN.Len() > 0 {
+		tlsOptions.ALPN = []string(*tlsSettings.ALPN)
+	}
+	certificatePath := ""
+	for i, certConfig := range tlsSettings.Certs {
+		currentPath := v2box.IndexPath(path+".certificates", i)
+		switch strings.ToLower(certConfig.Usage) {
+		case "", "encipherment":
+		case "issue":
+			report.Warn(currentPath, tag, "certificate authority for issuing certificates is not migrated, sing-box does not issue certificates on the fly")
+			continue
+		case "verify":
+			report.Warn(currentPath, tag, "certificate authority for verifying clients is not migrated, sing-box does not verify client certificates")
+			continue
+		default:
+			report.Warn(currentPath, tag, "certificate with usage ", certConfig.Usage, " is not migrated")
+			continue
+		}
+		if certificatePath != "" {
+			report.Warn(currentPath, tag, "certificate is not migrated, sing-box serves only ", certificatePath, " to every server name")
+			continue
+		}
+		certificatePath = currentPath
+		if len(certConfig.CertStr) > 0 {
+			tlsOptions.Certificate = strings.Join(certConfig.CertStr, "\n")
+		}
+		if len(certConfig.KeyStr) > 0 {
+			tlsOptions.Key = strings.Join(certConfig.KeyStr, "\n")
+		}
+		tlsOptions.CertificatePath = certConfig.CertFile
+		tlsOptions.KeyPath = certConfig.KeyFile
+	}
+	if tlsSettings.VerifyClientCertificate {
+		report.Warn(path+".verifyClientCertificate", tag, "client certificate verification is not migrated, clients connect without certificates")
+	}
+	if tlsSettings.DisableSystemRoot {
+		report.Info(path+".disableSystemRoot", tag, "disableSystemRoot is not migrated, it only applies to client certificate verification")
+	}
+	return tlsOptions
+}
+
+// parseOutboundTLS converts the TLS settings of an outbound.
+// V2Ray trusts certificates with usage verify in addition to the system roots, while a sing-box certificate
+// replaces them, so only the first one is kept, and client certificates and pins are reported.
+func parseOutboundTLS(tlsSettings *tlscfg.TLSConfig, path string, tag string, report *v2box.Report) option.OutboundTLSOptions {
+	tlsOptions := option.OutboundTLSOptions{Enabled: true}
+	if tlsSettings == nil {
+		return tlsOptions
+	}
+	tlsOptions.Insecure = tlsSettings.Insecure
+	tlsOptions.ServerName = tlsSettings.ServerName
+	if tlsSettings.ALPN != nil && tlsSettings.ALPN.Len() > 0 {
+		tlsOptions.ALPN = []string(*tlsSettings.ALPN)
+	}
+	certificatePath := ""
+	for i, certConfig := range tlsSettings.Certs {
+		currentPath := v2box.IndexPath(path+".certificates", i)
+		switch strings.ToLower(certConfig.Usage) {
+		case "verify":
+		case "", "encipherment":
+			report.Warn(currentPath, tag, "client certificate is not migrated, sing-box does not present client certificates")
+			continue
+		default:
+			report.Warn(currentPath, tag, "certificate with usage ", certConfig.Usage, " is not migrated")
+			continue
+		}
+		if certificatePath != "" {
+			report.Warn(currentPath, tag, "certificate is not migrated, sing-box trusts only ", certificatePath)
+			continue
+		}
+		certificatePath = currentPath
+		if len(certConfig.CertStr) > 0 {
+			tlsOptions.Certificate = strings.Join(certConfig.CertStr, "\n")
+		}
+		tlsOptions.CertificatePath = certConfig.CertFile
+	}
+	if certificatePath != "" && !tlsSettings.DisableSystemRoot {
+		report.Info(certificatePath, tag, "system roots are no longer trusted besides the certificate")
+	} else if certificatePath == "" && tlsSettings.DisableSystemRoot {
+		report.Warn(path+".disableSystemRoot", tag, "disableSystemRoot without certificates to verify is not migrated, system roots are trusted")
+	}
+	if tlsSettings.PinnedPeerCertificateChainSha256 != nil && len(*tlsSettings.PinnedPeerCertificateChainSha256) > 0 {
+		report.Warn(path+".pinnedPeerCertificateChainSha256", tag, "pinned peer certificate chain is not migrated, sing-box does not verify certificate pins")
+	}
+	if tlsSettings.EnableSessionResumption {
+		report.Info(path+".enableSessionResumption", tag, "session resumption is not migrated")
+	}
+	return tlsOptions
+}
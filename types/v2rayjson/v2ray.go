@@ -1,14 +1,11 @@
 package v2rayjson
 
 import (
-	"bytes"
 	"strings"
 
-	"github.com/sagernet/sing-box/common/json"
-	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
-	"github.com/sagernet/sing/common/format"
+	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	"github.com/sagernet/v2box"
 
@@ -18,55 +15,104 @@ import (
 
 func init() {
 	v2box.Register("v2ray", strings.Join(core.VersionStatement(), "\n"), Migrate)
+	v2box.RegisterDetector("v2ray", Detect)
+	v2box.RegisterMerger("v2ray", Merge)
+	v2box.RegisterExporter("v2ray", Export)
 }
 
-func Migrate(content []byte, logger logger.Logger) (option.Options, error) {
+func Migrate(content []byte, migrateOptions v2box.MigrateOptions, logger logger.Logger) (option.Options, v2box.Report, error) {
 	var options option.Options
+	var report v2box.Report
 	var v2rayConfig v4json.Config
-	decoder := json.NewDecoder(json.NewCommentFilter(bytes.NewReader(content)))
-	err := decoder.Decode(&v2rayConfig)
+	err := v2box.DecodeJSON(content, &v2rayConfig)
 	if err != nil {
-		return option.Options{}, err
+		return option.Options{}, v2box.Report{}, err
 	}
+	domainStrategies, err := parseSockoptDomainStrategy(content)
+	if err != nil {
+		return option.Options{}, v2box.Report{}, err
+	}
+	inboundTags := make(map[string][]string)
+	// tags generated for ports must not take the tag of another inbound
+	inboundTagSet := v2box.NewTagSet(common.Map(v2rayConfig.InboundConfigs, func(it v4json.InboundDetourConfig) string {
+		return it.Tag
+	})...)
 	for i, inboundConfig := range v2rayConfig.InboundConfigs {
-		inbound, err := migrateInbound(inboundConfig)
+		path := v2box.IndexPath("inbounds", i)
+		inbounds, err := migrateInbound(inboundConfig, domainStrategies[i], inboundTagSet, migrateOptions.InboundPortLimit(), path, &report)
 		if err != nil {
-			tag := inboundConfig.Tag
-			if tag == "" {
-				tag = format.ToString(i)
-			}
-			logger.Warn("ignoring inbound ", tag, ": ", err)
+			report.Drop(path, inboundConfig.Tag, err)
 			continue
 		}
-		options.Inbounds = append(options.Inbounds, inbound)
-	}
-	outboundServerRule := option.DNSRule{
-		Type: C.RuleTypeDefault,
-		DefaultOptions: option.DefaultDNSRule{
-			Server: "local",
-		},
+		if len(inbounds) > 1 && inboundConfig.Tag != "" {
+			inboundTags[inboundConfig.Tag] = common.Map(inbounds, func(it option.Inbound) string {
+				return it.Tag
+			})
+		}
+		options.Inbounds = append(options.Inbounds, inbounds...)
 	}
+	// tags generated for endpoints must not take the tag of another outbound
+	outboundTags := v2box.NewTagSet(common.Map(v2rayConfig.OutboundConfigs, func(it v4json.OutboundDetourConfig) string {
+		return it.Tag
+	})...)
+	var outboundServerRule option.DefaultDNSRule
 	for i, outboundConfig := range v2rayConfig.OutboundConfigs {
-		outbound, err := migrateOutbound(outboundConfig, &outboundServerRule.DefaultOptions)
+		path := v2box.IndexPath("outbounds", i)
+		outbounds, err := migrateOutbound(outboundConfig, outboundTags, path, &report, &outboundServerRule)
 		if err != nil {
-			tag := outboundConfig.Tag
-			if tag == "" {
-				tag = format.ToString(i)
-			}
-			logger.Warn("ignoring outbound ", tag, ": ", err)
+			report.Drop(path, outboundConfig.Tag, err)
 			continue
 		}
-		options.Outbounds = append(options.Outbounds, outbound)
+		options.Outbounds = append(options.Outbounds, outbounds...)
 	}
-	migrateDNS(common.PtrValueOrDefault(v2rayConfig.DNSConfig), &options)
-	if len(outboundServerRule.DefaultOptions.Domain) > 0 {
-		options.DNS.Rules = append(options.DNS.Rules, outboundServerRule)
+	// balancer and observatory selectors match configured outbounds only, not other groups or injected outbounds
+	outbounds := options.Outbounds
+	hosts, err := v2box.ParseHosts(content)
+	if err != nil {
+		return option.Options{}, v2box.Report{}, err
 	}
+	hostRoutes := migrateDNS(common.PtrValueOrDefault(v2rayConfig.DNSConfig), hosts, outboundServerRule, &options, outboundTags, migrateOptions, &report)
+	var observatoryUsed, burstObservatoryUsed bool
 	if routerConfig := v2rayConfig.RouterConfig; routerConfig != nil {
-		for _, ruleMessage := range routerConfig.RuleList {
-			rule, err := migrateRule(ruleMessage)
+		if routerConfig.DomainStrategy != nil && !strings.EqualFold(*routerConfig.DomainStrategy, "AsIs") {
+			report.Warn("routing.domainStrategy", "", "domain strategy ", *routerConfig.DomainStrategy, " is not migrated")
+		}
+		var balancerTags []string
+		for i, balancer := range routerConfig.Balancers {
+			path := v2box.IndexPath("routing.balancers", i)
+			var urlTestOptions option.URLTestOutboundOptions
+			var usesObservatory, usesBurstObservatory bool
+			switch strings.ToLower(balancer.Strategy.Type) {
+			case "leastping":
+				if v2rayConfig.Observatory != nil {
+					urlTestOptions = parseObservatory(v2rayConfig.Observatory)
+					usesObservatory = true
+				}
+			case "leastload":
+				if v2rayConfig.BurstObservatory != nil {
+					urlTestOptions = parseBurstObservatory(v2rayConfig.BurstObservatory)
+					usesBurstObservatory = true
+				}
+				err := reportStrategySettings(balancer.Strategy.Settings, path+".strategy", balancer.Tag, &report)
+				if err != nil {
+					report.Drop(path, balancer.Tag, v2box.WrapPathError("strategy", err))
+					continue
+				}
+			}
+			outbound, err := migrateBalancer(balancer.Tag, balancer.Selectors, balancer.Strategy.Type, balancer.FallbackTag, urlTestOptions, outbounds, outboundTags, path, &report)
 			if err != nil {
-				logger.Warn("ignoring rule: ", err)
+				report.Drop(path, balancer.Tag, err)
+				continue
+			}
+			options.Outbounds = append(options.Outbounds, outbound)
+			balancerTags = append(balancerTags, balancer.Tag)
+			observatoryUsed = observatoryUsed || usesObservatory
+			burstObservatoryUsed = burstObservatoryUsed || usesBurstObservatory
+		}
+		for i, ruleMessage := range routerConfig.RuleList {
+			rule, err := migrateRule(ruleMessage, balancerTags, inboundTags)
+			if err != nil {
+				report.Drop(v2box.IndexPath("routing.rules", i), "", err)
 				continue
 			}
 			if options.Route == nil {
@@ -75,5 +121,30 @@ func Migrate(content []byte, logger logger.Logger) (option.Options, error) {
 			options.Route.Rules = append(options.Route.Rules, rule)
 		}
 	}
-	return options, nil
+	if v2rayConfig.FakeDNS != nil {
+		reportFakeDNS(v2rayConfig.FakeDNS, "fakeDns", "", &report)
+	}
+	if v2rayConfig.Reverse != nil {
+		report.Drop("reverse", "", E.New("reverse proxy is not supported"))
+	}
+	if v2rayConfig.Observatory != nil && !observatoryUsed {
+		outbound, err := migrateObservatory("observatory", v2rayConfig.Observatory.SubjectSelector, parseObservatory(v2rayConfig.Observatory), outbounds, outboundTags)
+		if err != nil {
+			report.Drop("observatory", "", err)
+		} else {
+			options.Outbounds = append(options.Outbounds, outbound)
+			report.Info("observatory", "", "observatory is migrated to urltest outbound ", outbound.Tag)
+		}
+	}
+	if v2rayConfig.BurstObservatory != nil && !burstObservatoryUsed {
+		outbound, err := migrateObservatory("burstObservatory", v2rayConfig.BurstObservatory.SubjectSelector, parseBurstObservatory(v2rayConfig.BurstObservatory), outbounds, outboundTags)
+		if err != nil {
+			report.Drop("burstObservatory", "", err)
+		} else {
+			options.Outbounds = append(options.Outbounds, outbound)
+			report.Info("burstObservatory", "", "burst observatory is migrated to urltest outbound ", outbound.Tag)
+		}
+	}
+	v2box.RouteHosts(hostRoutes, &options, &report)
+	return options, report, nil
 }
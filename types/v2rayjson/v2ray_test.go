@@ -0,0 +1,185 @@
+package v2rayjson
+
+import (
+	"strings"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/v2box"
+)
+
+func migrateTest(t *testing.T, content string) (option.Options, v2box.Report) {
+	t.Helper()
+	options, report, err := Migrate([]byte(content), v2box.MigrateOptions{}, log.StdLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return options, report
+}
+
+func outboundTags(options option.Options) []string {
+	tags := make([]string, 0, len(options.Outbounds))
+	for _, outbound := range options.Outbounds {
+		tags = append(tags, outbound.Tag)
+	}
+	return tags
+}
+
+func findOutbound(options option.Options, tag string) (option.Outbound, bool) {
+	for _, outbound := range options.Outbounds {
+		if outbound.Tag == tag {
+			return outbound, true
+		}
+	}
+	return option.Outbound{}, false
+}
+
+func hasReport(report v2box.Report, severity v2box.Severity, path string) bool {
+	for _, entry := range report.Entries {
+		if entry.Severity == severity && entry.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMigrateHostsAfterRules(t *testing.T) {
+	options, report := migrateTest(t, `{
+		"dns": {"hosts": {"x.com": "1.2.3.4", "y.com": "1.2.3.4"}},
+		"outbounds": [
+			{"protocol": "freedom", "tag": "direct"},
+			{"protocol": "freedom", "tag": "hosts-1.2.3.4"},
+			{"protocol": "blackhole", "tag": "proxy"}
+		],
+		"routing": {"rules": [{"type": "field", "domain": ["full:x.com"], "outboundTag": "proxy"}]}
+	}`)
+	rules := options.Route.Rules
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	if rules[0].DefaultOptions.Outbound != "proxy" {
+		t.Errorf("expected the routing rule first, got outbound %s", rules[0].DefaultOptions.Outbound)
+	}
+	hostRule := rules[1].DefaultOptions
+	if hostRule.Outbound != "hosts-1.2.3.4-2" {
+		t.Errorf("expected a unique hosts outbound tag, got %s", hostRule.Outbound)
+	}
+	if len(hostRule.Domain) != 2 || hostRule.Domain[0] != "x.com" || hostRule.Domain[1] != "y.com" {
+		t.Errorf("unexpected hosts rule domains %v", hostRule.Domain)
+	}
+	outbound, loaded := findOutbound(options, hostRule.Outbound)
+	if !loaded || outbound.Type != C.TypeDirect || outbound.DirectOptions.OverrideAddress != "1.2.3.4" {
+		t.Errorf("unexpected hosts outbound %+v", outbound)
+	}
+	if !hasReport(report, v2box.SeverityWarning, "dns.hosts.x.com") {
+		t.Error("expected a warning for dns.hosts.x.com")
+	}
+}
+
+func TestMigrateHostsProxiedFinal(t *testing.T) {
+	options, report := migrateTest(t, `{
+		"dns": {"hosts": {"x.com": "1.2.3.4", "ads.com": "0.0.0.0"}},
+		"outbounds": [
+			{"protocol": "blackhole", "tag": "proxy"},
+			{"protocol": "freedom", "tag": "direct"}
+		]
+	}`)
+	if options.Route != nil && len(options.Route.Rules) > 0 {
+		t.Errorf("expected no hosts rules, got %+v", options.Route.Rules)
+	}
+	if _, loaded := findOutbound(options, "hosts-1.2.3.4"); loaded {
+		t.Errorf("expected no hosts outbound, got %v", outboundTags(options))
+	}
+	if !hasReport(report, v2box.SeverityError, "dns.hosts.x.com") {
+		t.Error("expected dns.hosts.x.com to be dropped")
+	}
+	if options.DNS == nil || len(options.DNS.Rules) != 1 || options.DNS.Rules[0].DefaultOptions.Server != "block" {
+		t.Error("expected the unspecified host to be answered by the block server")
+	}
+}
+
+func TestMigrateDNSInjectedTags(t *testing.T) {
+	options, _ := migrateTest(t, `{
+		"dns": {"servers": [{"address": "1.1.1.1", "tag": "local"}, "https+local://dns.google/dns-query"]},
+		"outbounds": [{"protocol": "vmess", "tag": "direct", "settings": {"vnext": [{"address": "1.2.3.4", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811"}]}]}}]
+	}`)
+	if expected := []string{"direct", "direct-2"}; strings.Join(outboundTags(options), ",") != strings.Join(expected, ",") {
+		t.Errorf("expected outbounds %v, got %v", expected, outboundTags(options))
+	}
+	if outbound, _ := findOutbound(options, "direct-2"); outbound.Type != C.TypeDirect {
+		t.Errorf("expected an injected direct outbound, got %s", outbound.Type)
+	}
+	var servers []string
+	for _, server := range options.DNS.Servers {
+		servers = append(servers, server.Tag+":"+server.Address+":"+server.AddressResolver+":"+server.Detour)
+	}
+	expected := []string{"local:1.1.1.1::", "dns-1:https://dns.google/dns-query:local-2:direct-2", "local-2:local::direct-2"}
+	if strings.Join(servers, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected servers %v, got %v", expected, servers)
+	}
+}
+
+func TestMigrateMultiplex(t *testing.T) {
+	options, report := migrateTest(t, `{
+		"outbounds": [{"protocol": "vmess", "tag": "proxy", "settings": {"vnext": [{"address": "1.2.3.4", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811"}]}]}, "mux": {"enabled": true}}]
+	}`)
+	if outbound, _ := findOutbound(options, "proxy"); outbound.Type != C.TypeVMess || outbound.VMessOptions.Multiplex != nil {
+		t.Error("expected a vmess outbound without multiplex")
+	}
+	if !hasReport(report, v2box.SeverityError, "outbounds[0].mux") {
+		t.Error("expected outbounds[0].mux to be dropped")
+	}
+}
+
+func TestMigrateInboundDomainStrategy(t *testing.T) {
+	_, report := migrateTest(t, `{
+		"inbounds": [
+			{"protocol": "socks", "port": 1080, "streamSettings": {"sockopt": {"domainStrategy": "UseIPv4"}}},
+			{"protocol": "socks", "port": 1081, "streamSettings": {"sockopt": {"domainStrategy": "AsIs"}}}
+		]
+	}`)
+	if !hasReport(report, v2box.SeverityInfo, "inbounds[0].streamSettings.sockopt.domainStrategy") {
+		t.Error("expected an info for inbounds[0].streamSettings.sockopt.domainStrategy")
+	}
+	if hasReport(report, v2box.SeverityInfo, "inbounds[1].streamSettings.sockopt.domainStrategy") {
+		t.Error("expected no info for AsIs")
+	}
+}
+
+func TestMigrateLocateError(t *testing.T) {
+	for _, testCase := range []struct {
+		name    string
+		format  string
+		content string
+		line    string
+	}{
+		{
+			name:    "yaml listen",
+			format:  v2box.FormatYAML,
+			content: "inbounds:\n  - protocol: socks\n    port: 1080\n    listen: [1, 2]\n",
+			line:    "line 4: inbounds[0].listen: ",
+		},
+		{
+			name:    "toml port",
+			format:  v2box.FormatTOML,
+			content: "[[inbounds]]\nprotocol = \"socks\"\nport = \"a-b\"\n",
+			line:    "line 3: inbounds[0].port: ",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			content, lines, err := v2box.ConvertFormat(testCase.format, []byte(testCase.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = Migrate(content, v2box.MigrateOptions{}, log.StdLogger())
+			if err == nil {
+				t.Fatal("expected a decode error")
+			}
+			if err = lines.LocateError(content, err); !strings.HasPrefix(err.Error(), testCase.line) {
+				t.Errorf("expected %s, got %v", testCase.line, err)
+			}
+		})
+	}
+}
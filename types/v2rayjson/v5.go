@@ -0,0 +1,151 @@
+package v2rayjson
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/v2box"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/router"
+	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/v5cfg"
+)
+
+func init() {
+	v2box.Register("v2ray5", strings.Join(core.VersionStatement(), "\n"), MigrateV5)
+	v2box.RegisterDetector("v2ray5", DetectV5)
+	v2box.RegisterMerger("v2ray5", Merge)
+}
+
+func MigrateV5(content []byte, migrateOptions v2box.MigrateOptions, logger logger.Logger) (option.Options, v2box.Report, error) {
+	var options option.Options
+	var report v2box.Report
+	var v2rayConfig v5cfg.RootConfig
+	err := v2box.DecodeJSON(content, &v2rayConfig)
+	if err != nil {
+		return option.Options{}, v2box.Report{}, err
+	}
+	inboundTags := make(map[string][]string)
+	// tags generated for ports must not take the tag of another inbound
+	inboundTagSet := v2box.NewTagSet(common.Map(v2rayConfig.Inbounds, func(it v5cfg.InboundConfig) string {
+		return it.Tag
+	})...)
+	for i, inboundConfig := range v2rayConfig.Inbounds {
+		path := v2box.IndexPath("inbounds", i)
+		inbounds, err := migrateInboundV5(inboundConfig, inboundTagSet, migrateOptions.InboundPortLimit(), path, &report)
+		if err != nil {
+			report.Drop(path, inboundConfig.Tag, err)
+			continue
+		}
+		if len(inbounds) > 1 && inboundConfig.Tag != "" {
+			inboundTags[inboundConfig.Tag] = common.Map(inbounds, func(it option.Inbound) string {
+				return it.Tag
+			})
+		}
+		options.Inbounds = append(options.Inbounds, inbounds...)
+	}
+	// tags generated for endpoints must not take the tag of another outbound
+	outboundTags := v2box.NewTagSet(common.Map(v2rayConfig.Outbounds, func(it v5cfg.OutboundConfig) string {
+		return it.Tag
+	})...)
+	var outboundServerRule option.DefaultDNSRule
+	for i, outboundConfig := range v2rayConfig.Outbounds {
+		path := v2box.IndexPath("outbounds", i)
+		outbounds, err := migrateOutboundV5(outboundConfig, outboundTags, path, &report, &outboundServerRule)
+		if err != nil {
+			report.Drop(path, outboundConfig.Tag, err)
+			continue
+		}
+		options.Outbounds = append(options.Outbounds, outbounds...)
+	}
+	// selectors match configured outbounds only, not balancers or injected outbounds
+	outbounds := options.Outbounds
+	dnsConfig, hosts, err := convertDNSV5(v2rayConfig.DNSConfig, &report)
+	if err != nil {
+		report.Drop("dns", "", err)
+	}
+	hostRoutes := migrateDNS(dnsConfig, hosts, outboundServerRule, &options, outboundTags, migrateOptions, &report)
+	if len(v2rayConfig.RouterConfig) > 0 {
+		var routerConfig router.SimplifiedConfig
+		err = unmarshalJSONPB(v2rayConfig.RouterConfig, &routerConfig)
+		if err != nil {
+			report.Drop("router", "", err)
+		} else {
+			if routerConfig.DomainStrategy != router.DomainStrategy_AsIs {
+				report.Warn("router.domainStrategy", "", "domain strategy ", routerConfig.DomainStrategy.String(), " is not migrated")
+			}
+			var balancerTags []string
+			for i, balancer := range routerConfig.BalancingRule {
+				path := v2box.IndexPath("router.balancingRule", i)
+				reportStrategySettingsV5(balancer, path, &report)
+				outbound, err := migrateBalancer(balancer.Tag, balancer.OutboundSelector, balancer.Strategy, balancer.FallbackTag, option.URLTestOutboundOptions{}, outbounds, outboundTags, path, &report)
+				if err != nil {
+					report.Drop(path, balancer.Tag, err)
+					continue
+				}
+				options.Outbounds = append(options.Outbounds, outbound)
+				balancerTags = append(balancerTags, balancer.Tag)
+			}
+			for i, ruleConfig := range routerConfig.Rule {
+				rule, err := migrateRuleV5(ruleConfig, balancerTags, inboundTags)
+				if err != nil {
+					report.Drop(v2box.IndexPath("router.rule", i), "", err)
+					continue
+				}
+				if options.Route == nil {
+					options.Route = &option.RouteOptions{}
+				}
+				options.Route.Rules = append(options.Route.Rules, rule)
+			}
+		}
+	}
+	serviceNames := make([]string, 0, len(v2rayConfig.Services))
+	for name := range v2rayConfig.Services {
+		serviceNames = append(serviceNames, name)
+	}
+	sort.Strings(serviceNames)
+	for _, name := range serviceNames {
+		report.Drop(v2box.JoinPath("services", name), "", E.New("service is not supported"))
+	}
+	for i := range v2rayConfig.Extensions {
+		report.Drop(v2box.IndexPath("extension", i), "", E.New("extension is not supported"))
+	}
+	v2box.RouteHosts(hostRoutes, &options, &report)
+	return options, report, nil
+}
+
+// unmarshalJSONPB decodes a v5 config message without running the v2ray loaders,
+// which would read geo resources and certificate files from the local filesystem.
+func unmarshalJSONPB(content []byte, message proto.Message) error {
+	if len(content) == 0 {
+		return nil
+	}
+	return (&jsonpb.Unmarshaler{}).Unmarshal(bytes.NewReader(content), message)
+}
+
+// reportStrategySettingsV5 reports the settings of a leastLoad balancer, which urltest has no equivalent for.
+// Observatories are v5 services and are not migrated, so urltest uses the default probe options.
+func reportStrategySettingsV5(balancer *router.BalancingRule, path string, report *v2box.Report) {
+	if balancer.StrategySettings == nil {
+		return
+	}
+	settings, err := serial.GetInstanceOf(balancer.StrategySettings)
+	if err != nil {
+		return
+	}
+	leastLoad, isLeastLoad := settings.(*router.StrategyLeastLoadConfig)
+	if !isLeastLoad {
+		return
+	}
+	if len(leastLoad.Costs) > 0 || len(leastLoad.Baselines) > 0 || leastLoad.Expected != 0 || leastLoad.MaxRTT != 0 || leastLoad.Tolerance != 0 {
+		report.Warn(path+".strategy_settings", balancer.Tag, leastLoadNotMigrated)
+	}
+}
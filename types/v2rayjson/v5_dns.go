@@ -0,0 +1,174 @@
+package v2rayjson
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
+
+	"github.com/v2fly/v2ray-core/v5/app/dns"
+	v2ray_net "github.com/v2fly/v2ray-core/v5/common/net"
+	conf_dns "github.com/v2fly/v2ray-core/v5/infra/conf/synthetic/dns"
+)
+
+// dnsConfigV4 and nameServerV4 are the v4 JSON forms of the DNS config,
+// so v5 DNS settings share migrateDNS.
+type dnsConfigV4 struct {
+	Servers                []nameServerV4 `json:"servers,omitempty"`
+	ClientIP               string         `json:"clientIp,omitempty"`
+	Tag                    string         `json:"tag,omitempty"`
+	QueryStrategy          string         `json:"queryStrategy,omitempty"`
+	CacheStrategy          string         `json:"cacheStrategy,omitempty"`
+	FallbackStrategy       string         `json:"fallbackStrategy,omitempty"`
+	DisableCache           bool           `json:"disableCache,omitempty"`
+	DisableFallback        bool           `json:"disableFallback,omitempty"`
+	DisableFallbackIfMatch bool           `json:"disableFallbackIfMatch,omitempty"`
+}
+
+type nameServerV4 struct {
+	Address          string   `json:"address"`
+	Port             uint32   `json:"port,omitempty"`
+	ClientIP         string   `json:"clientIp,omitempty"`
+	Tag              string   `json:"tag,omitempty"`
+	QueryStrategy    string   `json:"queryStrategy,omitempty"`
+	CacheStrategy    string   `json:"cacheStrategy,omitempty"`
+	FallbackStrategy string   `json:"fallbackStrategy,omitempty"`
+	SkipFallback     bool     `json:"skipFallback,omitempty"`
+	Domains          []string `json:"domains,omitempty"`
+	ExpectIPs        []string `json:"expectIps,omitempty"`
+}
+
+func convertDNSV5(content json.RawMessage, report *v2box.Report) (conf_dns.DNSConfig, map[string]option.Listable[string], error) {
+	if len(content) == 0 {
+		return conf_dns.DNSConfig{}, nil, nil
+	}
+	var dnsConfig dns.SimplifiedConfig
+	err := unmarshalJSONPB(content, &dnsConfig)
+	if err != nil {
+		return conf_dns.DNSConfig{}, nil, err
+	}
+	reportFakeDNSPools(dnsConfig.FakeDns, "dns.fakeDns", dnsConfig.Tag, report)
+	configV4 := dnsConfigV4{
+		ClientIP:               dnsConfig.ClientIp,
+		Tag:                    dnsConfig.Tag,
+		QueryStrategy:          convertQueryStrategyV5(dnsConfig.QueryStrategy),
+		CacheStrategy:          convertCacheStrategyV5(dnsConfig.CacheStrategy),
+		FallbackStrategy:       convertFallbackStrategyV5(dnsConfig.FallbackStrategy),
+		DisableCache:           dnsConfig.DisableCache,
+		DisableFallback:        dnsConfig.DisableFallback,
+		DisableFallbackIfMatch: dnsConfig.DisableFallbackIfMatch,
+	}
+	for i, server := range dnsConfig.NameServer {
+		serverV4 := nameServerV4{
+			ClientIP:     server.ClientIp,
+			Tag:          server.Tag,
+			SkipFallback: server.SkipFallback,
+		}
+		if server.FakeDns != nil {
+			reportFakeDNSPools(server.FakeDns, v2box.IndexPath("dns.nameServer", i)+".fakeDns", server.Tag, report)
+			serverV4.Address = "fakedns"
+		} else if server.Address == nil || server.Address.Address == nil {
+			report.Drop(v2box.IndexPath("dns.nameServer", i), server.Tag, E.New("missing server address"))
+			continue
+		} else {
+			serverV4.Address = server.Address.Address.AsAddress().String()
+			if server.Address.Network == v2ray_net.Network_TCP && !strings.Contains(serverV4.Address, "://") {
+				serverV4.Address = "tcp://" + serverV4.Address
+			}
+			serverV4.Port = server.Address.Port
+		}
+		if server.QueryStrategy != nil {
+			serverV4.QueryStrategy = convertQueryStrategyV5(*server.QueryStrategy)
+		}
+		if server.CacheStrategy != nil {
+			serverV4.CacheStrategy = convertCacheStrategyV5(*server.CacheStrategy)
+		}
+		if server.FallbackStrategy != nil {
+			serverV4.FallbackStrategy = convertFallbackStrategyV5(*server.FallbackStrategy)
+		}
+		for _, domain := range server.PrioritizedDomain {
+			serverV4.Domains = append(serverV4.Domains, convertDomainMatchingTypeV5(domain.Type)+domain.Domain)
+		}
+		serverV4.ExpectIPs, err = convertGeoIPV5(server.Geoip)
+		if err != nil {
+			return conf_dns.DNSConfig{}, nil, v2box.WrapPathError(v2box.IndexPath("nameServer", i), err)
+		}
+		configV4.Servers = append(configV4.Servers, serverV4)
+	}
+	var hosts map[string]option.Listable[string]
+	if len(dnsConfig.StaticHosts) > 0 {
+		hosts = make(map[string]option.Listable[string])
+		for _, host := range dnsConfig.StaticHosts {
+			domain := convertDomainMatchingTypeV5(host.Type) + host.Domain
+			if host.ProxiedDomain != "" {
+				hosts[domain] = append(hosts[domain], host.ProxiedDomain)
+			}
+			for _, ip := range host.Ip {
+				// addresses are parsed from text by V2Ray at load time, but decoded as raw bytes here
+				if address := M.ParseAddr(string(ip)); address.IsValid() {
+					hosts[domain] = append(hosts[domain], address.String())
+				} else {
+					hosts[domain] = append(hosts[domain], net.IP(ip).String())
+				}
+			}
+		}
+	}
+	dnsMessage, err := json.Marshal(configV4)
+	if err != nil {
+		return conf_dns.DNSConfig{}, nil, err
+	}
+	var decodedConfig conf_dns.DNSConfig
+	err = json.Unmarshal(dnsMessage, &decodedConfig)
+	if err != nil {
+		return conf_dns.DNSConfig{}, nil, err
+	}
+	return decodedConfig, hosts, nil
+}
+
+func convertDomainMatchingTypeV5(domainType dns.DomainMatchingType) string {
+	switch domainType {
+	case dns.DomainMatchingType_Subdomain:
+		return "domain:"
+	case dns.DomainMatchingType_Keyword:
+		return "keyword:"
+	case dns.DomainMatchingType_Regex:
+		return "regexp:"
+	default:
+		return "full:"
+	}
+}
+
+func convertQueryStrategyV5(queryStrategy dns.QueryStrategy) string {
+	switch queryStrategy {
+	case dns.QueryStrategy_USE_IP4:
+		return "UseIPv4"
+	case dns.QueryStrategy_USE_IP6:
+		return "UseIPv6"
+	default:
+		return "UseIP"
+	}
+}
+
+func convertCacheStrategyV5(cacheStrategy dns.CacheStrategy) string {
+	switch cacheStrategy {
+	case dns.CacheStrategy_CacheDisabled:
+		return "disabled"
+	default:
+		return "enabled"
+	}
+}
+
+func convertFallbackStrategyV5(fallbackStrategy dns.FallbackStrategy) string {
+	switch fallbackStrategy {
+	case dns.FallbackStrategy_Disabled:
+		return "disabled"
+	case dns.FallbackStrategy_DisabledIfAnyMatch:
+		return "disabledIfAnyMatch"
+	default:
+		return "enabled"
+	}
+}
@@ -0,0 +1,142 @@
+package v2rayjson
+
+import (
+	"context"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/v5cfg"
+	"github.com/v2fly/v2ray-core/v5/proxy/dokodemo"
+	"github.com/v2fly/v2ray-core/v5/proxy/http"
+	http_simplified "github.com/v2fly/v2ray-core/v5/proxy/http/simplified"
+	"github.com/v2fly/v2ray-core/v5/proxy/shadowsocks"
+	shadowsocks_simplified "github.com/v2fly/v2ray-core/v5/proxy/shadowsocks/simplified"
+	"github.com/v2fly/v2ray-core/v5/proxy/socks"
+	socks_simplified "github.com/v2fly/v2ray-core/v5/proxy/socks/simplified"
+	"github.com/v2fly/v2ray-core/v5/proxy/trojan"
+	trojan_simplified "github.com/v2fly/v2ray-core/v5/proxy/trojan/simplified"
+	"github.com/v2fly/v2ray-core/v5/proxy/vless"
+	vless_inbound "github.com/v2fly/v2ray-core/v5/proxy/vless/inbound"
+	"github.com/v2fly/v2ray-core/v5/proxy/vmess"
+	vmess_inbound "github.com/v2fly/v2ray-core/v5/proxy/vmess/inbound"
+)
+
+func migrateInboundV5(inboundConfig v5cfg.InboundConfig, inboundTags v2box.TagSet, maxPorts int, path string, report *v2box.Report) ([]option.Inbound, error) {
+	tag := inboundConfig.Tag
+
+	var listenOptions option.ListenOptions
+	if inboundConfig.ListenOn != nil {
+		listenOptions.Listen = option.NewListenAddress(M.ParseAddr(inboundConfig.ListenOn.Address.String()))
+	}
+	listenOptions.InboundOptions = parseSniffing(inboundConfig.SniffingConfig, path+".sniffing", tag, report)
+
+	var tlsOptions option.InboundTLSOptions
+	var transportOptions option.V2RayTransportOptions
+	var tproxyName string
+	var err error
+
+	if streamSettings := inboundConfig.StreamSetting; streamSettings != nil {
+		socketSettings := streamSettings.SocketSettings
+		if socketSettings.TFO != nil {
+			listenOptions.TCPFastOpen = *socketSettings.TFO
+		}
+		tproxyName = socketSettings.TProxy
+		if socketSettings.AcceptProxyProtocol {
+			listenOptions.ProxyProtocol = true
+			listenOptions.ProxyProtocolAcceptNoHeader = true
+		}
+		transportOptions, err = parseTransportV5(streamSettings)
+		if err != nil {
+			return nil, err
+		}
+		tlsOptions, err = parseInboundTLSV5(streamSettings, path, tag, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+	proxySettings, err := v5cfg.LoadHeterogeneousConfigFromRawJSON(context.Background(), "inbound", inboundConfig.Protocol, inboundConfig.Settings)
+	if err != nil {
+		return nil, v2box.WrapPathError("settings", err)
+	}
+	inbound, err := newInbound(tag, fullInboundConfigV5(proxySettings), listenOptions, tproxyName, tlsOptions, transportOptions)
+	if err != nil {
+		return nil, err
+	}
+	switch inbound.Type {
+	case C.TypeVMess, C.TypeVLESS, C.TypeTrojan:
+	default:
+		if transportOptions.Type != "" {
+			report.Warn(path+".streamSettings.transport", inbound.Tag, "transport is not supported by ", inbound.Type, " inbound")
+		}
+		if tlsOptions.Enabled && inbound.Type != C.TypeHTTP {
+			report.Warn(path+".streamSettings.security", inbound.Tag, "TLS is not supported by ", inbound.Type, " inbound")
+		}
+	}
+	if portRange := inboundConfig.PortRange; portRange != nil {
+		return migratePorts(inbound, listPortRange(portRange.From, portRange.To), F.ToString(portRange.From, "-", portRange.To), inboundTags, maxPorts, path+".port", report), nil
+	}
+	return []option.Inbound{inbound}, nil
+}
+
+// fullInboundConfigV5 expands the simplified v5 inbound configs into the full configs handled by newInbound.
+func fullInboundConfigV5(proxySettings proto.Message) any {
+	switch proxyType := proxySettings.(type) {
+	case *http_simplified.ServerConfig:
+		return &http.ServerConfig{}
+	case *socks_simplified.ServerConfig:
+		return &socks.ServerConfig{
+			AuthType:   socks.AuthType_NO_AUTH,
+			Address:    proxyType.Address,
+			UdpEnabled: proxyType.UdpEnabled,
+		}
+	case *shadowsocks_simplified.ServerConfig:
+		return &shadowsocks.ServerConfig{
+			User: &protocol.User{
+				Account: serial.ToTypedMessage(&shadowsocks.Account{
+					Password:   proxyType.Password,
+					CipherType: proxyType.Method,
+				}),
+			},
+			Network: proxyType.Networks.GetNetwork(),
+		}
+	case *trojan_simplified.ServerConfig:
+		fullServer := &trojan.ServerConfig{}
+		for _, password := range proxyType.Users {
+			fullServer.Users = append(fullServer.Users, &protocol.User{
+				Account: serial.ToTypedMessage(&trojan.Account{Password: password}),
+			})
+		}
+		return fullServer
+	case *vmess_inbound.SimplifiedConfig:
+		fullServer := &vmess_inbound.Config{}
+		for _, uuid := range proxyType.Users {
+			fullServer.User = append(fullServer.User, &protocol.User{
+				Account: serial.ToTypedMessage(&vmess.Account{Id: uuid}),
+			})
+		}
+		return fullServer
+	case *vless_inbound.SimplifiedConfig:
+		fullServer := &vless_inbound.Config{Decryption: "none"}
+		for _, uuid := range proxyType.Users {
+			fullServer.Clients = append(fullServer.Clients, &protocol.User{
+				Account: serial.ToTypedMessage(&vless.Account{Id: uuid}),
+			})
+		}
+		return fullServer
+	case *dokodemo.SimplifiedConfig:
+		return &dokodemo.Config{
+			Address:        proxyType.Address,
+			Port:           proxyType.Port,
+			Networks:       proxyType.Networks.GetNetwork(),
+			FollowRedirect: proxyType.FollowRedirect,
+		}
+	}
+	return proxySettings
+}
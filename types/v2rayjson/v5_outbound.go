@@ -0,0 +1,180 @@
+package v2rayjson
+
+import (
+	"context"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/v2box"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/v5cfg"
+	"github.com/v2fly/v2ray-core/v5/proxy/blackhole"
+	proxy_dns "github.com/v2fly/v2ray-core/v5/proxy/dns"
+	"github.com/v2fly/v2ray-core/v5/proxy/freedom"
+	"github.com/v2fly/v2ray-core/v5/proxy/http"
+	http_simplified "github.com/v2fly/v2ray-core/v5/proxy/http/simplified"
+	"github.com/v2fly/v2ray-core/v5/proxy/shadowsocks"
+	shadowsocks_simplified "github.com/v2fly/v2ray-core/v5/proxy/shadowsocks/simplified"
+	"github.com/v2fly/v2ray-core/v5/proxy/socks"
+	socks_simplified "github.com/v2fly/v2ray-core/v5/proxy/socks/simplified"
+	"github.com/v2fly/v2ray-core/v5/proxy/trojan"
+	trojan_simplified "github.com/v2fly/v2ray-core/v5/proxy/trojan/simplified"
+	"github.com/v2fly/v2ray-core/v5/proxy/vless"
+	vless_outbound "github.com/v2fly/v2ray-core/v5/proxy/vless/outbound"
+	"github.com/v2fly/v2ray-core/v5/proxy/vmess"
+	vmess_outbound "github.com/v2fly/v2ray-core/v5/proxy/vmess/outbound"
+)
+
+func migrateOutboundV5(outboundConfig v5cfg.OutboundConfig, outboundTags v2box.TagSet, path string, report *v2box.Report, dnsRule *option.DefaultDNSRule) ([]option.Outbound, error) {
+	tag := outboundConfig.Tag
+
+	var dialOptions option.DialerOptions
+	var tlsOptions option.OutboundTLSOptions
+	var transportOptions option.V2RayTransportOptions
+	var err error
+
+	if streamSettings := outboundConfig.StreamSetting; streamSettings != nil {
+		parseSocketOptions(&streamSettings.SocketSettings, &dialOptions, path+".streamSettings.socketSettings", tag, report)
+		transportOptions, err = parseTransportV5(streamSettings)
+		if err != nil {
+			return nil, err
+		}
+		tlsOptions, err = parseOutboundTLSV5(streamSettings, path, tag, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if proxySettings := outboundConfig.ProxySettings; proxySettings != nil && proxySettings.Tag != "" {
+		dialOptions.Detour = proxySettings.Tag
+		if !proxySettings.TransportLayerProxy && (transportOptions.Type != "" || tlsOptions.Enabled) {
+			// V2Ray sends the proxy protocol through the upstream outbound without stream settings
+			transportOptions = option.V2RayTransportOptions{}
+			tlsOptions = option.OutboundTLSOptions{}
+			report.Info(path+".streamSettings", tag, "stream settings are ignored by proxy chaining without transportLayer")
+		}
+		// sing-box sends the server domain of a chained outbound to the upstream proxy, which resolves it
+		dnsRule = nil
+	}
+	if outboundConfig.SendThrough != nil {
+		err = parseSendThrough(outboundConfig.SendThrough, &dialOptions)
+		if err != nil {
+			return nil, v2box.WrapPathError("sendThrough", err)
+		}
+	}
+	proxySettings, err := v5cfg.LoadHeterogeneousConfigFromRawJSON(context.Background(), "outbound", outboundConfig.Protocol, outboundConfig.Settings)
+	if err != nil {
+		return nil, v2box.WrapPathError("settings", err)
+	}
+	outbound, servers, err := newOutbound(tag, fullOutboundConfigV5(proxySettings), tlsOptions, transportOptions)
+	if err != nil {
+		return nil, err
+	}
+	switch outbound.Type {
+	case C.TypeVMess, C.TypeVLESS, C.TypeTrojan:
+	default:
+		if transportOptions.Type != "" {
+			report.Warn(path+".streamSettings.transport", outbound.Tag, "transport is not supported by ", outbound.Type, " outbound")
+		}
+		if tlsOptions.Enabled && outbound.Type != C.TypeHTTP {
+			report.Warn(path+".streamSettings.security", outbound.Tag, "TLS is not supported by ", outbound.Type, " outbound")
+		}
+	}
+	if dialOptions != (option.DialerOptions{}) && !setDialerOptions(&outbound, dialOptions) {
+		report.Warn(path, tag, "dialer options are not supported by ", outbound.Type, " outbound")
+	}
+	migrateMultiplex(&outbound, outboundConfig.MuxSettings, path+".mux", report)
+	return migrateEndpoints(outbound, servers, outboundTags, path, report, dnsRule)
+}
+
+// fullOutboundConfigV5 expands the simplified v5 outbound configs into the full configs handled by newOutbound.
+func fullOutboundConfigV5(proxySettings proto.Message) any {
+	switch proxyType := proxySettings.(type) {
+	case *blackhole.SimplifiedConfig:
+		return &blackhole.Config{}
+	case *proxy_dns.SimplifiedConfig:
+		return &proxy_dns.Config{}
+	case *freedom.SimplifiedConfig:
+		return &freedom.Config{}
+	case *http_simplified.ClientConfig:
+		return &http.ClientConfig{
+			Server: []*protocol.ServerEndpoint{
+				{
+					Address: proxyType.Address,
+					Port:    proxyType.Port,
+				},
+			},
+		}
+	case *socks_simplified.ClientConfig:
+		return &socks.ClientConfig{
+			Server: []*protocol.ServerEndpoint{
+				{
+					Address: proxyType.Address,
+					Port:    proxyType.Port,
+				},
+			},
+		}
+	case *shadowsocks_simplified.ClientConfig:
+		return &shadowsocks.ClientConfig{
+			Server: []*protocol.ServerEndpoint{
+				{
+					Address: proxyType.Address,
+					Port:    proxyType.Port,
+					User: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&shadowsocks.Account{
+								Password:   proxyType.Password,
+								CipherType: proxyType.Method,
+							}),
+						},
+					},
+				},
+			},
+		}
+	case *trojan_simplified.ClientConfig:
+		return &trojan.ClientConfig{
+			Server: []*protocol.ServerEndpoint{
+				{
+					Address: proxyType.Address,
+					Port:    proxyType.Port,
+					User: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&trojan.Account{Password: proxyType.Password}),
+						},
+					},
+				},
+			},
+		}
+	case *vmess_outbound.SimplifiedConfig:
+		return &vmess_outbound.Config{
+			Receiver: []*protocol.ServerEndpoint{
+				{
+					Address: proxyType.Address,
+					Port:    proxyType.Port,
+					User: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vmess.Account{Id: proxyType.Uuid}),
+						},
+					},
+				},
+			},
+		}
+	case *vless_outbound.SimplifiedConfig:
+		return &vless_outbound.Config{
+			Vnext: []*protocol.ServerEndpoint{
+				{
+					Address: proxyType.Address,
+					Port:    proxyType.Port,
+					User: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vless.Account{Id: proxyType.Uuid, Encryption: "none"}),
+						},
+					},
+				},
+			},
+		}
+	}
+	return proxySettings
+}
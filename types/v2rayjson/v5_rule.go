@@ -0,0 +1,127 @@
+package v2rayjson
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/v2fly/v2ray-core/v5/app/router"
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+)
+
+// fieldRuleV4 is the v4 JSON form of a routing rule, so v5 rules share migrateRule.
+type fieldRuleV4 struct {
+	Type        string   `json:"type"`
+	OutboundTag string   `json:"outboundTag,omitempty"`
+	BalancerTag string   `json:"balancerTag,omitempty"`
+	Domain      []string `json:"domain,omitempty"`
+	IP          []string `json:"ip,omitempty"`
+	Port        string   `json:"port,omitempty"`
+	Network     string   `json:"network,omitempty"`
+	SourceIP    []string `json:"source,omitempty"`
+	SourcePort  string   `json:"sourcePort,omitempty"`
+	User        []string `json:"user,omitempty"`
+	InboundTag  []string `json:"inboundTag,omitempty"`
+	Protocols   []string `json:"protocol,omitempty"`
+	Attributes  string   `json:"attrs,omitempty"`
+}
+
+func migrateRuleV5(ruleConfig *router.SimplifiedRoutingRule, balancerTags []string, inboundTags map[string][]string) (option.Rule, error) {
+	fieldRule := fieldRuleV4{
+		Type:        "field",
+		OutboundTag: ruleConfig.GetTag(),
+		BalancerTag: ruleConfig.GetBalancingTag(),
+		Domain:      convertDomainsV5(ruleConfig.Domain),
+		Port:        ruleConfig.PortList,
+		SourcePort:  ruleConfig.SourcePortList,
+		User:        ruleConfig.UserEmail,
+		InboundTag:  ruleConfig.InboundTag,
+		Protocols:   ruleConfig.Protocol,
+		Attributes:  ruleConfig.Attributes,
+	}
+	for _, geoDomain := range ruleConfig.GeoDomain {
+		code := geoDomain.Code
+		if code == "" {
+			code = geoDomain.CountryCode
+		}
+		if geoDomain.FilePath != "" {
+			fieldRule.Domain = append(fieldRule.Domain, F.ToString("ext:", geoDomain.FilePath, ":", code))
+		} else {
+			fieldRule.Domain = append(fieldRule.Domain, "geosite:"+code)
+		}
+	}
+	var err error
+	fieldRule.IP, err = convertGeoIPV5(ruleConfig.Geoip)
+	if err != nil {
+		return option.Rule{}, err
+	}
+	fieldRule.SourceIP, err = convertGeoIPV5(ruleConfig.SourceGeoip)
+	if err != nil {
+		return option.Rule{}, err
+	}
+	if networks := ruleConfig.Networks.GetNetwork(); len(networks) > 0 {
+		networkNames := make([]string, 0, len(networks))
+		for _, network := range networks {
+			networkNames = append(networkNames, strings.ToLower(network.String()))
+		}
+		fieldRule.Network = strings.Join(networkNames, ",")
+	}
+	ruleMessage, err := json.Marshal(fieldRule)
+	if err != nil {
+		return option.Rule{}, err
+	}
+	return migrateRule(ruleMessage, balancerTags, inboundTags)
+}
+
+func convertDomainsV5(domains []*routercommon.Domain) []string {
+	var domainList []string
+	for _, domain := range domains {
+		switch domain.Type {
+		case routercommon.Domain_Plain:
+			domainList = append(domainList, "keyword:"+domain.Value)
+		case routercommon.Domain_Regex:
+			domainList = append(domainList, "regexp:"+domain.Value)
+		case routercommon.Domain_RootDomain:
+			domainList = append(domainList, "domain:"+domain.Value)
+		case routercommon.Domain_Full:
+			domainList = append(domainList, "full:"+domain.Value)
+		}
+	}
+	return domainList
+}
+
+func convertGeoIPV5(geoipList []*routercommon.GeoIP) ([]string, error) {
+	var addressList []string
+	for _, geoip := range geoipList {
+		code := geoip.Code
+		if code == "" {
+			code = geoip.CountryCode
+		}
+		if code != "" {
+			if geoip.InverseMatch {
+				code = "!" + code
+			}
+			if geoip.FilePath != "" {
+				addressList = append(addressList, F.ToString("ext:", geoip.FilePath, ":", code))
+			} else {
+				addressList = append(addressList, "geoip:"+code)
+			}
+			continue
+		}
+		if geoip.InverseMatch {
+			return nil, E.New("inverse CIDR match is not supported")
+		}
+		for _, cidr := range geoip.Cidr {
+			address := cidr.IpAddr
+			if address == "" {
+				address = net.IP(cidr.Ip).String()
+			}
+			addressList = append(addressList, F.ToString(address, "/", cidr.Prefix))
+		}
+	}
+	return addressList, nil
+}
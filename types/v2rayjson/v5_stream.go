@@ -0,0 +1,210 @@
+package v2rayjson
+
+import (
+	"context"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/v2box"
+
+	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/v5cfg"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/grpc"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/headers/noop"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/http"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/quic"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tcp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls/utls"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/websocket"
+)
+
+func parseTransportV5(streamSettings *v5cfg.StreamConfig) (option.V2RayTransportOptions, error) {
+	if streamSettings.Transport == "" {
+		return option.V2RayTransportOptions{}, nil
+	}
+	transportSettings, err := v5cfg.LoadHeterogeneousConfigFromRawJSON(context.Background(), "transport", streamSettings.Transport, streamSettings.TransportSettings)
+	if err != nil {
+		return option.V2RayTransportOptions{}, v2box.WrapPathError("streamSettings.transportSettings", err)
+	}
+	var transportOptions option.V2RayTransportOptions
+	switch transportConfig := transportSettings.(type) {
+	case *tcp.Config:
+		if transportConfig.HeaderSettings != nil {
+			header, err := serial.GetInstanceOf(transportConfig.HeaderSettings)
+			if err != nil {
+				return option.V2RayTransportOptions{}, v2box.WrapPathError("streamSettings.transportSettings.headerSettings", err)
+			}
+			if _, isNoop := header.(*noop.Config); !isNoop {
+				return option.V2RayTransportOptions{}, v2box.NewPathError("streamSettings.transportSettings.headerSettings", "unsupported v2ray TCP transport with header")
+			}
+		}
+	case *http.Config:
+		transportOptions.Type = C.V2RayTransportTypeHTTP
+		transportOptions.HTTPOptions.Host = transportConfig.Host
+		transportOptions.HTTPOptions.Path = transportConfig.Path
+		transportOptions.HTTPOptions.Method = transportConfig.Method
+		if len(transportConfig.Header) > 0 {
+			transportOptions.HTTPOptions.Headers = make(map[string]string)
+			for _, header := range transportConfig.Header {
+				if len(header.Value) == 0 {
+					continue
+				}
+				transportOptions.HTTPOptions.Headers[header.Name] = header.Value[0]
+			}
+		}
+	case *websocket.Config:
+		transportOptions.Type = C.V2RayTransportTypeWebsocket
+		transportOptions.WebsocketOptions.Path = transportConfig.Path
+		if len(transportConfig.Header) > 0 {
+			transportOptions.WebsocketOptions.Headers = make(map[string]string)
+			for _, header := range transportConfig.Header {
+				transportOptions.WebsocketOptions.Headers[header.Key] = header.Value
+			}
+		}
+		transportOptions.WebsocketOptions.MaxEarlyData = uint32(transportConfig.MaxEarlyData)
+		transportOptions.WebsocketOptions.EarlyDataHeaderName = transportConfig.EarlyDataHeaderName
+	case *grpc.Config:
+		transportOptions.Type = C.V2RayTransportTypeGRPC
+		transportOptions.GRPCOptions.ServiceName = transportConfig.ServiceName
+	case *quic.Config:
+		transportOptions.Type = C.V2RayTransportTypeQUIC
+	default:
+		return option.V2RayTransportOptions{}, v2box.NewPathError("streamSettings.transport", "unsupported v2ray transport type: ", streamSettings.Transport)
+	}
+	return transportOptions, nil
+}
+
+func parseInboundTLSV5(streamSettings *v5cfg.StreamConfig, path string, tag string, report *v2box.Report) (option.InboundTLSOptions, error) {
+	var tlsOptions option.InboundTLSOptions
+	switch streamSettings.Security {
+	case "", "none":
+	case "tls":
+		var tlsConfig tls.Config
+		err := unmarshalJSONPB(streamSettings.SecuritySettings, &tlsConfig)
+		if err != nil {
+			return option.InboundTLSOptions{}, v2box.WrapPathError("streamSettings.securitySettings", err)
+		}
+		tlsOptions.Enabled = true
+		tlsOptions.ServerName = tlsConfig.ServerName
+		certificatePath := ""
+		for i, certificate := range tlsConfig.Certificate {
+			currentPath := v2box.IndexPath(path+".streamSettings.securitySettings.certificate", i)
+			switch certificate.Usage {
+			case tls.Certificate_ENCIPHERMENT:
+			case tls.Certificate_AUTHORITY_ISSUE:
+				report.Warn(currentPath, tag, "certificate authority for issuing certificates is not migrated, sing-box does not issue certificates on the fly")
+				continue
+			case tls.Certificate_AUTHORITY_VERIFY:
+				report.Warn(currentPath, tag, "certificate authority for verifying clients is not migrated, sing-box does not verify client certificates")
+				continue
+			default:
+				report.Warn(currentPath, tag, "certificate with usage ", certificate.Usage.String(), " is not migrated")
+				continue
+			}
+			if certificatePath != "" {
+				report.Warn(currentPath, tag, "certificate is not migrated, sing-box serves only ", certificatePath, " to every server name")
+				continue
+			}
+			certificatePath = currentPath
+			if len(certificate.Certificate) > 0 {
+				tlsOptions.Certificate = string(certificate.Certificate)
+			}
+			if len(certificate.Key) > 0 {
+				tlsOptions.Key = string(certificate.Key)
+			}
+			tlsOptions.CertificatePath = certificate.CertificateFile
+			tlsOptions.KeyPath = certificate.KeyFile
+		}
+		tlsOptions.ALPN = tlsConfig.NextProtocol
+		if tlsConfig.VerifyClientCertificate {
+			report.Warn(path+".streamSettings.securitySettings.verifyClientCertificate", tag, "client certificate verification is not migrated, clients connect without certificates")
+		}
+		if tlsConfig.DisableSystemRoot {
+			report.Info(path+".streamSettings.securitySettings.disableSystemRoot", tag, "disableSystemRoot is not migrated, it only applies to client certificate verification")
+		}
+	default:
+		report.Warn(path+".streamSettings.security", tag, "unsupported security ", streamSettings.Security, " is not migrated")
+	}
+	return tlsOptions, nil
+}
+
+func parseOutboundTLSV5(streamSettings *v5cfg.StreamConfig, path string, tag string, report *v2box.Report) (option.OutboundTLSOptions, error) {
+	var tlsConfig *tls.Config
+	var tlsOptions option.OutboundTLSOptions
+	switch streamSettings.Security {
+	case "", "none":
+		return option.OutboundTLSOptions{}, nil
+	case "tls":
+		tlsConfig = new(tls.Config)
+		err := unmarshalJSONPB(streamSettings.SecuritySettings, tlsConfig)
+		if err != nil {
+			return option.OutboundTLSOptions{}, v2box.WrapPathError("streamSettings.securitySettings", err)
+		}
+	case "utls":
+		var utlsConfig utls.Config
+		err := unmarshalJSONPB(streamSettings.SecuritySettings, &utlsConfig)
+		if err != nil {
+			return option.OutboundTLSOptions{}, v2box.WrapPathError("streamSettings.securitySettings", err)
+		}
+		tlsConfig = utlsConfig.TlsConfig
+		if tlsConfig == nil {
+			tlsConfig = new(tls.Config)
+		}
+		tlsOptions.UTLS = &option.OutboundUTLSOptions{
+			Enabled:     true,
+			Fingerprint: parseUTLSImitate(utlsConfig.Imitate),
+		}
+	default:
+		report.Warn(path+".streamSettings.security", tag, "unsupported security ", streamSettings.Security, " is not migrated")
+		return option.OutboundTLSOptions{}, nil
+	}
+	tlsOptions.Enabled = true
+	tlsOptions.Insecure = tlsConfig.AllowInsecure
+	tlsOptions.ServerName = tlsConfig.ServerName
+	certificatePath := ""
+	for i, certificate := range tlsConfig.Certificate {
+		currentPath := v2box.IndexPath(path+".streamSettings.securitySettings.certificate", i)
+		switch certificate.Usage {
+		case tls.Certificate_AUTHORITY_VERIFY:
+		case tls.Certificate_ENCIPHERMENT:
+			report.Warn(currentPath, tag, "client certificate is not migrated, sing-box does not present client certificates")
+			continue
+		default:
+			report.Warn(currentPath, tag, "certificate with usage ", certificate.Usage.String(), " is not migrated")
+			continue
+		}
+		if certificatePath != "" {
+			report.Warn(currentPath, tag, "certificate is not migrated, sing-box trusts only ", certificatePath)
+			continue
+		}
+		certificatePath = currentPath
+		if len(certificate.Certificate) > 0 {
+			tlsOptions.Certificate = string(certificate.Certificate)
+		}
+		tlsOptions.CertificatePath = certificate.CertificateFile
+	}
+	if certificatePath != "" && !tlsConfig.DisableSystemRoot {
+		report.Info(certificatePath, tag, "system roots are no longer trusted besides the certificate")
+	} else if certificatePath == "" && tlsConfig.DisableSystemRoot {
+		report.Warn(path+".streamSettings.securitySettings.disableSystemRoot", tag, "disableSystemRoot without certificates to verify is not migrated, system roots are trusted")
+	}
+	if len(tlsConfig.PinnedPeerCertificateChainSha256) > 0 {
+		report.Warn(path+".streamSettings.securitySettings.pinnedPeerCertificateChainSha256", tag, "pinned peer certificate chain is not migrated, sing-box does not verify certificate pins")
+	}
+	if tlsConfig.EnableSessionResumption {
+		report.Info(path+".streamSettings.securitySettings.enableSessionResumption", tag, "session resumption is not migrated")
+	}
+	tlsOptions.ALPN = tlsConfig.NextProtocol
+	return tlsOptions, nil
+}
+
+// parseUTLSImitate maps a v2ray uTLS preset such as chrome_102 to a sing-box fingerprint.
+func parseUTLSImitate(imitate string) string {
+	if strings.HasPrefix(imitate, "randomized") {
+		return "randomized"
+	}
+	fingerprint, _, _ := strings.Cut(imitate, "_")
+	return fingerprint
+}
@@ -0,0 +1,243 @@
+package v2rayjson
+
+import (
+	"reflect"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/v2box"
+)
+
+func migrateV5Test(t *testing.T, content string) (option.Options, v2box.Report) {
+	t.Helper()
+	options, report, err := MigrateV5([]byte(content), v2box.MigrateOptions{}, log.StdLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return options, report
+}
+
+func TestMigrateV5Routing(t *testing.T) {
+	for _, testCase := range []struct {
+		name     string
+		rule     string
+		expected option.DefaultRule
+		dropped  bool
+	}{
+		{
+			name: "domain",
+			rule: `{"tag": "block", "domain": [
+				{"type": "RootDomain", "value": "ads.com"},
+				{"type": "Full", "value": "a.com"},
+				{"type": "Plain", "value": "kw"},
+				{"type": "Regex", "value": "^r"}
+			]}`,
+			expected: option.DefaultRule{
+				Domain:        []string{"ads.com", "a.com"},
+				DomainSuffix:  []string{".ads.com"},
+				DomainKeyword: []string{"kw"},
+				DomainRegex:   []string{"^r"},
+				Outbound:      "block",
+			},
+		},
+		{
+			name: "geoip and cidr",
+			rule: `{"tag": "direct", "portList": "53,443", "networks": "tcp", "geoip": [
+				{"cidr": [{"ipAddr": "10.0.0.0", "prefix": 8}, {"ipAddr": "1.1.1.1", "prefix": 32}]},
+				{"code": "cn"}
+			]}`,
+			expected: option.DefaultRule{
+				Network:  "tcp",
+				GeoIP:    []string{"cn"},
+				IPCIDR:   []string{"10.0.0.0/8", "1.1.1.1/32"},
+				Port:     []uint16{53, 443},
+				Outbound: "direct",
+			},
+		},
+		{
+			name: "geosite and inbound",
+			rule: `{"tag": "direct", "geoDomain": [{"code": "cn"}], "inboundTag": ["in"]}`,
+			expected: option.DefaultRule{
+				Inbound:  []string{"in"},
+				Geosite:  []string{"cn"},
+				Outbound: "direct",
+			},
+		},
+		{
+			name:    "inverse cidr",
+			rule:    `{"tag": "direct", "geoip": [{"inverseMatch": true, "cidr": [{"ipAddr": "10.0.0.0", "prefix": 8}]}]}`,
+			dropped: true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			options, report := migrateV5Test(t, `{
+				"outbounds": [{"protocol": "freedom", "tag": "direct"}, {"protocol": "blackhole", "tag": "block"}],
+				"router": {"rule": [`+testCase.rule+`]}
+			}`)
+			if testCase.dropped {
+				if !hasReport(report, v2box.SeverityError, "router.rule[0]") {
+					t.Error("expected router.rule[0] to be dropped")
+				}
+				if options.Route != nil && len(options.Route.Rules) > 0 {
+					t.Errorf("expected no rules, got %+v", options.Route.Rules)
+				}
+				return
+			}
+			if options.Route == nil || len(options.Route.Rules) != 1 {
+				t.Fatalf("expected 1 rule, got %+v", options.Route)
+			}
+			rule := options.Route.Rules[0]
+			if rule.Type != C.RuleTypeDefault || !reflect.DeepEqual(rule.DefaultOptions, testCase.expected) {
+				t.Errorf("expected rule %+v, got %+v", testCase.expected, rule.DefaultOptions)
+			}
+		})
+	}
+}
+
+func TestMigrateV5DNS(t *testing.T) {
+	for _, testCase := range []struct {
+		name       string
+		nameServer string
+		address    string
+		dropped    bool
+	}{
+		{
+			name:       "udp",
+			nameServer: `{"address": {"address": "8.8.8.8", "port": 53}}`,
+			address:    "8.8.8.8",
+		},
+		{
+			name:       "tcp",
+			nameServer: `{"address": {"address": "1.0.0.1", "port": 53, "network": "TCP"}}`,
+			address:    "tcp://1.0.0.1",
+		},
+		{
+			name:       "https",
+			nameServer: `{"address": {"address": "https://1.1.1.1/dns-query"}}`,
+			address:    "https://1.1.1.1/dns-query",
+		},
+		{
+			name:       "missing address",
+			nameServer: `{}`,
+			dropped:    true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			options, report := migrateV5Test(t, `{
+				"outbounds": [{"protocol": "freedom", "tag": "direct"}],
+				"dns": {"nameServer": [`+testCase.nameServer+`]}
+			}`)
+			if testCase.dropped {
+				if !hasReport(report, v2box.SeverityError, "dns.nameServer[0]") {
+					t.Error("expected dns.nameServer[0] to be dropped")
+				}
+				if options.DNS != nil && len(options.DNS.Servers) > 0 {
+					t.Errorf("expected no servers, got %+v", options.DNS.Servers)
+				}
+				return
+			}
+			if options.DNS == nil || len(options.DNS.Servers) != 1 {
+				t.Fatalf("expected 1 server, got %+v", options.DNS)
+			}
+			if address := options.DNS.Servers[0].Address; address != testCase.address {
+				t.Errorf("expected address %s, got %s", testCase.address, address)
+			}
+		})
+	}
+}
+
+func TestMigrateV5DNSRules(t *testing.T) {
+	options, _ := migrateV5Test(t, `{
+		"outbounds": [{"protocol": "freedom", "tag": "direct"}],
+		"dns": {
+			"nameServer": [
+				{"address": {"address": "8.8.8.8", "port": 53}, "prioritizedDomain": [{"type": "Subdomain", "domain": "google.com"}]},
+				{"address": {"address": "1.1.1.1", "port": 53}}
+			],
+			"queryStrategy": "USE_IP4"
+		}
+	}`)
+	if options.DNS == nil || len(options.DNS.Rules) != 1 {
+		t.Fatalf("expected 1 DNS rule, got %+v", options.DNS)
+	}
+	rule := options.DNS.Rules[0].DefaultOptions
+	server := options.DNS.Servers[0].Tag
+	if rule.Server != server || !reflect.DeepEqual([]string(rule.Domain), []string{"google.com"}) || !reflect.DeepEqual([]string(rule.DomainSuffix), []string{".google.com"}) {
+		t.Errorf("expected google.com resolved by %s, got %+v", server, rule)
+	}
+	if options.DNS.Strategy != option.DomainStrategy(dns.DomainStrategyUseIPv4) {
+		t.Errorf("expected ipv4_only strategy, got %v", options.DNS.Strategy)
+	}
+}
+
+func TestMigrateV5Transport(t *testing.T) {
+	for _, testCase := range []struct {
+		name           string
+		streamSettings string
+		transport      option.V2RayTransportOptions
+		tls            bool
+		dropped        bool
+	}{
+		{
+			name:           "tcp",
+			streamSettings: `{"transport": "tcp"}`,
+		},
+		{
+			name:           "websocket with tls",
+			streamSettings: `{"transport": "ws", "transportSettings": {"path": "/ws"}, "security": "tls", "securitySettings": {"serverName": "a.example.com"}}`,
+			transport: option.V2RayTransportOptions{
+				Type:             C.V2RayTransportTypeWebsocket,
+				WebsocketOptions: option.V2RayWebsocketOptions{Path: "/ws"},
+			},
+			tls: true,
+		},
+		{
+			name:           "grpc",
+			streamSettings: `{"transport": "grpc", "transportSettings": {"serviceName": "a"}}`,
+			transport: option.V2RayTransportOptions{
+				Type:        C.V2RayTransportTypeGRPC,
+				GRPCOptions: option.V2RayGRPCOptions{ServiceName: "a"},
+			},
+		},
+		{
+			name:           "kcp",
+			streamSettings: `{"transport": "kcp"}`,
+			dropped:        true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			options, report := migrateV5Test(t, `{"outbounds": [{
+				"protocol": "vmess",
+				"tag": "proxy",
+				"settings": {"address": "a.example.com", "port": 443, "uuid": "b831381d-6324-4d53-ad4f-8cda48b30811"},
+				"streamSettings": `+testCase.streamSettings+`
+			}]}`)
+			if testCase.dropped {
+				if !hasReport(report, v2box.SeverityError, "outbounds[0].streamSettings.transport") {
+					t.Error("expected outbounds[0].streamSettings.transport to be dropped")
+				}
+				if len(options.Outbounds) > 0 {
+					t.Errorf("expected no outbounds, got %v", outboundTags(options))
+				}
+				return
+			}
+			outbound, loaded := findOutbound(options, "proxy")
+			if !loaded || outbound.Type != C.TypeVMess {
+				t.Fatalf("expected vmess outbound proxy, got %v", outboundTags(options))
+			}
+			var transport option.V2RayTransportOptions
+			if outbound.VMessOptions.Transport != nil {
+				transport = *outbound.VMessOptions.Transport
+			}
+			if !reflect.DeepEqual(transport, testCase.transport) {
+				t.Errorf("expected transport %+v, got %+v", testCase.transport, transport)
+			}
+			if tlsEnabled := outbound.VMessOptions.TLS != nil && outbound.VMessOptions.TLS.Enabled; tlsEnabled != testCase.tls {
+				t.Errorf("expected tls %v, got %v", testCase.tls, tlsEnabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package xrayjson
+
+import (
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/v2box"
+
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// migrateBalancer converts a routing balancer into a group outbound of the migrated outbounds matching its selectors.
+// leastPing balancers become urltest with the probe options of urlTestOptions, random balancers become selector.
+func migrateBalancer(tag string, selectors []string, strategy string, urlTestOptions option.URLTestOutboundOptions, outbounds []option.Outbound, outboundTags v2box.TagSet, path string, report *v2box.Report) (option.Outbound, error) {
+	if !outboundTags.Add(tag) {
+		return option.Outbound{}, v2box.NewPathError("tag", "tag ", tag, " is already used by an outbound or balancer")
+	}
+	selectedTags, err := selectOutbounds(selectors, outbounds)
+	if err != nil {
+		return option.Outbound{}, v2box.WrapPathError("selector", err)
+	}
+	switch strings.ToLower(strategy) {
+	case "leastping":
+		urlTestOptions.Outbounds = selectedTags
+		return option.Outbound{
+			Type:           C.TypeURLTest,
+			Tag:            tag,
+			URLTestOptions: urlTestOptions,
+		}, nil
+	case "", "random":
+		report.Info(path+".strategy", tag, "random balancer is migrated to selector with ", selectedTags[0], " selected")
+		return option.Outbound{
+			Type: C.TypeSelector,
+			Tag:  tag,
+			SelectorOptions: option.SelectorOutboundOptions{
+				Outbounds: selectedTags,
+			},
+		}, nil
+	default:
+		return option.Outbound{}, v2box.NewPathError("strategy", "unknown balancing strategy: ", strategy)
+	}
+}
+
+// migrateObservatory converts an observatory not used by any balancer into a urltest outbound,
+// so the outbounds it observes are still probed. The outbound takes the first free tag from tag.
+func migrateObservatory(tag string, selectors []string, urlTestOptions option.URLTestOutboundOptions, outbounds []option.Outbound, outboundTags v2box.TagSet) (option.Outbound, error) {
+	selectedTags, err := selectOutbounds(selectors, outbounds)
+	if err != nil {
+		return option.Outbound{}, v2box.WrapPathError("subjectSelector", err)
+	}
+	urlTestOptions.Outbounds = selectedTags
+	return option.Outbound{
+		Type:           C.TypeURLTest,
+		Tag:            outboundTags.New(tag),
+		URLTestOptions: urlTestOptions,
+	}, nil
+}
+
+// selectOutbounds returns the tags of outbounds matching any of the tag prefixes in selectors.
+func selectOutbounds(selectors []string, outbounds []option.Outbound) ([]string, error) {
+	var outboundTags []string
+	for _, outbound := range outbounds {
+		// groups among migrated outbounds only come from multiple endpoints, which are matched instead
+		if outbound.Tag == "" || outbound.Type == C.TypeURLTest || outbound.Type == C.TypeSelector {
+			continue
+		}
+		if common.Any(selectors, func(it string) bool {
+			return strings.HasPrefix(outbound.Tag, it)
+		}) {
+			outboundTags = append(outboundTags, outbound.Tag)
+		}
+	}
+	if len(outboundTags) == 0 {
+		return nil, E.New("no migrated outbound matches selector ", strings.Join(selectors, ", "))
+	}
+	return outboundTags, nil
+}
+
+func parseObservatory(observatoryConfig *conf.ObservatoryConfig) option.URLTestOutboundOptions {
+	return option.URLTestOutboundOptions{
+		URL:      observatoryConfig.ProbeURL,
+		Interval: option.Duration(observatoryConfig.ProbeInterval),
+	}
+}
@@ -8,33 +8,13 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
-	E "github.com/sagernet/sing/common/exceptions"
-	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/v2box"
 
 	v2ray_net "github.com/xtls/xray-core/common/net"
-	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/infra/conf"
 )
 
-func parseServerAddress(servers []*protocol.ServerEndpoint) (M.Socksaddr, []*protocol.User) {
-	if len(servers) == 0 {
-		return M.Socksaddr{}, nil
-	}
-	return M.ParseSocksaddrHostPort(servers[0].Address.AsAddress().String(), uint16(servers[0].Port)), servers[0].User
-}
-
-func parsePort(portList *conf.PortList) uint16 {
-	if portList == nil {
-		return 0
-	}
-	netPortList := portList.Build()
-	if len(netPortList.Range) == 0 {
-		return 0
-	}
-	return uint16(netPortList.Range[0].From)
-}
-
 func parseNetworkList(networks *conf.NetworkList) string {
 	if networks == nil {
 		return ""
@@ -82,7 +62,7 @@ func parseTransport(streamSettings *conf.StreamConfig) (option.V2RayTransportOpt
 	case "tcp":
 		if tcpSettings := streamSettings.TCPSettings; tcpSettings != nil {
 			if tcpSettings.HeaderConfig != nil {
-				return option.V2RayTransportOptions{}, E.New("unsupported v2ray TCP transport with header")
+				return option.V2RayTransportOptions{}, v2box.NewPathError("streamSettings.tcpSettings.header", "unsupported v2ray TCP transport with header")
 			}
 		}
 	case "http":
@@ -103,7 +83,7 @@ func parseTransport(streamSettings *conf.StreamConfig) (option.V2RayTransportOpt
 				}
 			}
 		}
-	case "ws":
+	case "websocket":
 		transportOptions.Type = C.V2RayTransportTypeWebsocket
 		if wsSettings := streamSettings.WSSettings; wsSettings != nil {
 			if wsSettings.Headers != nil {
@@ -145,7 +125,7 @@ func parseTransport(streamSettings *conf.StreamConfig) (option.V2RayTransportOpt
 	case "quic":
 		transportOptions.Type = C.V2RayTransportTypeQUIC
 	default:
-		return option.V2RayTransportOptions{}, E.New("unsupported v2ray transport type: ", networkName)
+		return option.V2RayTransportOptions{}, v2box.NewPathError("streamSettings.network", "unsupported v2ray transport type: ", networkName)
 	}
 	return transportOptions, nil
 }
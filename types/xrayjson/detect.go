@@ -0,0 +1,48 @@
+package xrayjson
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sagernet/v2box"
+)
+
+var outboundProtocolPath = regexp.MustCompile(`^outbounds\[\d+]\.protocol$`)
+
+func Detect(content []byte) v2box.Detection {
+	var detection v2box.Detection
+	err := v2box.WalkJSON(content, func(path string, key string, value any) {
+		stringValue, _ := value.(string)
+		switch key {
+		case "realitySettings", "xtlsSettings":
+			detection.Add(3, key, " at ", path)
+		case "security":
+			if stringValue == "reality" || stringValue == "xtls" {
+				detection.Add(3, stringValue, " security at ", path)
+			}
+		case "flow":
+			if strings.HasPrefix(stringValue, "xtls-rprx-") {
+				detection.Add(2, "flow ", stringValue, " at ", path)
+			}
+		case "method":
+			if strings.HasPrefix(stringValue, "2022-blake3-") {
+				detection.Add(2, "shadowsocks 2022 method at ", path)
+			}
+		case "protocol":
+			if stringValue == "wireguard" && outboundProtocolPath.MatchString(path) {
+				detection.Add(3, "wireguard outbound at ", path)
+			}
+		case "dialerProxy":
+			detection.Add(2, "dialer proxy at ", path)
+		case "fingerprint":
+			detection.Add(1, "uTLS fingerprint at ", path)
+		}
+		if path == "metrics" {
+			detection.Add(2, "xray-only section ", path)
+		}
+	})
+	if err != nil {
+		detection.Add(-10, "invalid JSON: ", err)
+	}
+	return detection
+}
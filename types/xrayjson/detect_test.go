@@ -0,0 +1,56 @@
+package xrayjson
+
+import (
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	for _, testCase := range []struct {
+		name    string
+		content string
+		score   int
+	}{
+		{
+			name: "reality",
+			content: `{"outbounds": [{"protocol": "vless", "settings": {"vnext": [{"users": [{"flow": "xtls-rprx-vision"}]}]},
+				"streamSettings": {"security": "reality", "realitySettings": {"fingerprint": "chrome"}}}]}`,
+			score: 9,
+		},
+		{
+			name: "wireguard and shadowsocks 2022",
+			content: `{"outbounds": [
+				{"protocol": "wireguard"},
+				{"protocol": "shadowsocks", "settings": {"servers": [{"method": "2022-blake3-aes-128-gcm"}]}, "streamSettings": {"sockopt": {"dialerProxy": "a"}}}
+			], "metrics": {}}`,
+			score: 9,
+		},
+		{
+			// wireguard inbounds exist in V2Ray too.
+			name:    "wireguard inbound",
+			content: `{"inbounds": [{"protocol": "wireguard"}]}`,
+			score:   0,
+		},
+		{
+			// features shared with V2Ray do not score.
+			name:    "ambiguous",
+			content: `{"outbounds": [{"protocol": "vmess", "streamSettings": {"network": "ws", "security": "tls"}}]}`,
+			score:   0,
+		},
+		{
+			name:    "v2ray gun",
+			content: `{"outbounds": [{"protocol": "vmess", "streamSettings": {"network": "gun"}}], "burstObservatory": {}}`,
+			score:   0,
+		},
+		{
+			name:    "invalid",
+			content: `{"outbounds": [`,
+			score:   -10,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if detection := Detect([]byte(testCase.content)); detection.Score != testCase.score {
+				t.Errorf("expected score %d, got %s", testCase.score, detection)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package xrayjson
+
+import (
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
+
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/proxy/http"
+	"github.com/xtls/xray-core/proxy/shadowsocks"
+	"github.com/xtls/xray-core/proxy/socks"
+	"github.com/xtls/xray-core/proxy/trojan"
+	"github.com/xtls/xray-core/proxy/vless"
+	"github.com/xtls/xray-core/proxy/vmess"
+)
+
+// migrateEndpoints sets each server and user of the outbound to a copy of it.
+// Xray picks among the endpoints of an outbound by itself, so multiple endpoints are grouped
+// by an outbound carrying the original tag, and routing to the tag keeps working:
+// the endpoints of multiple servers by urltest, to use the servers still available,
+// and the users of a single server by selector, as probing them gives the same result.
+func migrateEndpoints(outbound option.Outbound, servers []*protocol.ServerEndpoint, outboundTags v2box.TagSet, path string, report *v2box.Report, dnsRule *option.DefaultDNSRule) ([]option.Outbound, error) {
+	if len(servers) == 0 {
+		return []option.Outbound{outbound}, nil
+	}
+	serversPath := "settings.servers"
+	if outbound.Type == C.TypeVMess || outbound.Type == C.TypeVLESS {
+		serversPath = "settings.vnext"
+	}
+	var outbounds []option.Outbound
+	for i, server := range servers {
+		serverAddress := M.ParseSocksaddrHostPort(server.Address.AsAddress().String(), uint16(server.Port))
+		addServerToDNSOptions(serverAddress, dnsRule)
+		endpoint := outbound
+		setServerAddress(&endpoint, serverAddress)
+		if len(server.User) == 0 {
+			outbounds = append(outbounds, endpoint)
+			continue
+		}
+		for _, user := range server.User {
+			err := setUser(&endpoint, user)
+			if err != nil {
+				return nil, v2box.WrapPathError(v2box.IndexPath(serversPath, i), err)
+			}
+			outbounds = append(outbounds, endpoint)
+		}
+	}
+	if len(outbounds) == 1 {
+		return outbounds, nil
+	}
+	tagPrefix := outbound.Tag
+	if tagPrefix == "" {
+		tagPrefix = outbound.Type
+	}
+	endpointTags := make([]string, 0, len(outbounds))
+	for i := range outbounds {
+		outbounds[i].Tag = outboundTags.New(F.ToString(tagPrefix, "-", i+1))
+		endpointTags = append(endpointTags, outbounds[i].Tag)
+	}
+	group := option.Outbound{
+		Tag: outbound.Tag,
+	}
+	if len(servers) > 1 {
+		group.Type = C.TypeURLTest
+		group.URLTestOptions.Outbounds = endpointTags
+		report.Warn(path+"."+serversPath, outbound.Tag, len(outbounds), " endpoints are migrated to outbounds ", strings.Join(endpointTags, ", "), " grouped by urltest, which probes them with periodic HTTP requests")
+	} else {
+		group.Type = C.TypeSelector
+		group.SelectorOptions.Outbounds = endpointTags
+		report.Info(path+"."+serversPath, outbound.Tag, len(outbounds), " users are migrated to outbounds ", strings.Join(endpointTags, ", "), " grouped by selector with ", endpointTags[0], " selected")
+	}
+	return append([]option.Outbound{group}, outbounds...), nil
+}
+
+func setServerAddress(outbound *option.Outbound, serverAddress M.Socksaddr) {
+	serverOptions := option.ServerOptions{
+		Server:     serverAddress.AddrString(),
+		ServerPort: serverAddress.Port,
+	}
+	switch outbound.Type {
+	case C.TypeHTTP:
+		outbound.HTTPOptions.ServerOptions = serverOptions
+	case C.TypeSocks:
+		outbound.SocksOptions.ServerOptions = serverOptions
+	case C.TypeShadowsocks:
+		outbound.ShadowsocksOptions.ServerOptions = serverOptions
+	case C.TypeTrojan:
+		outbound.TrojanOptions.ServerOptions = serverOptions
+	case C.TypeVMess:
+		outbound.VMessOptions.ServerOptions = serverOptions
+	case C.TypeVLESS:
+		outbound.VLESSOptions.ServerOptions = serverOptions
+	}
+}
+
+func setUser(outbound *option.Outbound, user *protocol.User) error {
+	account, err := user.Account.GetInstance()
+	if err != nil {
+		return E.Cause(err, "get instance of ", user.Account.Type)
+	}
+	switch accountType := account.(type) {
+	case *http.Account:
+		outbound.HTTPOptions.Username = accountType.Username
+		outbound.HTTPOptions.Password = accountType.Password
+	case *socks.Account:
+		outbound.SocksOptions.Username = accountType.Username
+		outbound.SocksOptions.Password = accountType.Password
+	case *shadowsocks.Account:
+		var method string
+		switch accountType.CipherType {
+		case shadowsocks.CipherType_AES_128_GCM:
+			method = "aes-128-gcm"
+		case shadowsocks.CipherType_AES_256_GCM:
+			method = "aes-256-gcm"
+		case shadowsocks.CipherType_CHACHA20_POLY1305:
+			method = "chacha20-ietf-poly1305"
+		case shadowsocks.CipherType_NONE:
+			method = "none"
+		default:
+			return v2box.NewPathError("method", "unsupported shadowsocks cipher: ", accountType.CipherType)
+		}
+		outbound.ShadowsocksOptions.Method = method
+		outbound.ShadowsocksOptions.Password = accountType.Password
+	case *trojan.Account:
+		outbound.TrojanOptions.Password = accountType.Password
+	case *vmess.Account:
+		var security string
+		switch accountType.SecuritySettings.Type {
+		case protocol.SecurityType_AES128_GCM:
+			security = "aes-128-gcm"
+		case protocol.SecurityType_CHACHA20_POLY1305:
+			security = "chacha20-poly1305"
+		case protocol.SecurityType_NONE:
+			security = "none"
+		case protocol.SecurityType_ZERO:
+			security = "zero"
+		}
+		outbound.VMessOptions.UUID = accountType.Id
+		outbound.VMessOptions.Security = security
+		outbound.VMessOptions.AlterId = int(accountType.AlterId)
+		if strings.Contains(accountType.TestsEnabled, "AuthenticatedLength") {
+			outbound.VMessOptions.AuthenticatedLength = true
+		}
+	case *vless.Account:
+		outbound.VLESSOptions.UUID = accountType.Id
+		outbound.VLESSOptions.Flow = accountType.Flow
+	}
+	return nil
+}
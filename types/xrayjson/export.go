@@ -0,0 +1,269 @@
+package xrayjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/v2box"
+
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// exportConfig is the JSON configuration written by Export.
+type exportConfig struct {
+	Log       *exportLogConfig       `json:"log,omitempty"`
+	DNS       *exportDNSConfig       `json:"dns,omitempty"`
+	Routing   *exportRoutingConfig   `json:"routing,omitempty"`
+	Inbounds  []exportInboundConfig  `json:"inbounds,omitempty"`
+	Outbounds []exportOutboundConfig `json:"outbounds,omitempty"`
+}
+
+type exportLogConfig struct {
+	Error    string `json:"error,omitempty"`
+	LogLevel string `json:"loglevel,omitempty"`
+}
+
+type exportRoutingConfig struct {
+	Rules []exportRuleConfig `json:"rules,omitempty"`
+}
+
+type exportRuleConfig struct {
+	Type        string   `json:"type"`
+	OutboundTag string   `json:"outboundTag,omitempty"`
+	Domain      []string `json:"domain,omitempty"`
+	IP          []string `json:"ip,omitempty"`
+	Port        string   `json:"port,omitempty"`
+	Network     string   `json:"network,omitempty"`
+	SourceIP    []string `json:"source,omitempty"`
+	SourcePort  string   `json:"sourcePort,omitempty"`
+	User        []string `json:"user,omitempty"`
+	InboundTag  []string `json:"inboundTag,omitempty"`
+	Protocols   []string `json:"protocol,omitempty"`
+}
+
+// Export converts sing-box options into an Xray JSON configuration.
+func Export(options option.Options, logger logger.Logger) ([]byte, v2box.Report, error) {
+	var config exportConfig
+	var report v2box.Report
+	if options.Log != nil {
+		config.Log = exportLog(*options.Log)
+	}
+	for i, inbound := range options.Inbounds {
+		path := v2box.IndexPath("inbounds", i)
+		inboundConfig, err := exportInbound(inbound, path, &report)
+		if err != nil {
+			report.Drop(path, inbound.Tag, err)
+			continue
+		}
+		config.Inbounds = append(config.Inbounds, inboundConfig)
+	}
+	for i, outbound := range options.Outbounds {
+		path := v2box.IndexPath("outbounds", i)
+		outboundConfig, err := exportOutbound(outbound, path, &report)
+		if err != nil {
+			report.Drop(path, outbound.Tag, err)
+			continue
+		}
+		config.Outbounds = append(config.Outbounds, outboundConfig)
+	}
+	if options.Route != nil {
+		config.Routing = exportRoute(*options.Route, &config, &report)
+	}
+	if options.DNS != nil {
+		config.DNS = exportDNS(*options.DNS, options.Outbounds, &report)
+	}
+	if options.NTP != nil && options.NTP.Enabled {
+		report.Drop("ntp", "", E.New("NTP is not supported"))
+	}
+	if options.Experimental != nil {
+		report.Drop("experimental", "", E.New("experimental options are not supported"))
+	}
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, v2box.Report{}, err
+	}
+	var xrayConfig conf.Config
+	err = json.Unmarshal(content, &xrayConfig)
+	if err != nil {
+		return nil, v2box.Report{}, E.Cause(err, "decode exported configuration")
+	}
+	return content, report, nil
+}
+
+func exportLog(logOptions option.LogOptions) *exportLogConfig {
+	logConfig := exportLogConfig{
+		Error: logOptions.Output,
+	}
+	if logOptions.Disabled {
+		logConfig.LogLevel = "none"
+		return &logConfig
+	}
+	switch logOptions.Level {
+	case "trace", "debug":
+		logConfig.LogLevel = "debug"
+	case "info":
+		logConfig.LogLevel = "info"
+	case "warn":
+		logConfig.LogLevel = "warning"
+	case "error", "fatal", "panic":
+		logConfig.LogLevel = "error"
+	}
+	return &logConfig
+}
+
+func exportRoute(routeOptions option.RouteOptions, config *exportConfig, report *v2box.Report) *exportRoutingConfig {
+	var routingConfig exportRoutingConfig
+	for i, rule := range routeOptions.Rules {
+		fieldRules, err := exportRule(rule)
+		if err != nil {
+			report.Drop(v2box.IndexPath("route.rules", i), "", err)
+			continue
+		}
+		routingConfig.Rules = append(routingConfig.Rules, fieldRules...)
+	}
+	if routeOptions.Final != "" {
+		// Xray sends unmatched connections to the first outbound.
+		finalIndex := -1
+		for i, outboundConfig := range config.Outbounds {
+			if outboundConfig.Tag == routeOptions.Final {
+				finalIndex = i
+				break
+			}
+		}
+		if finalIndex == -1 {
+			report.Warn("route.final", "", "final outbound ", routeOptions.Final, " is not exported")
+		} else if finalIndex > 0 {
+			finalOutbound := config.Outbounds[finalIndex]
+			copy(config.Outbounds[1:finalIndex+1], config.Outbounds[:finalIndex])
+			config.Outbounds[0] = finalOutbound
+			report.Info("route.final", "", "final outbound ", routeOptions.Final, " is moved to the first outbound")
+		}
+	}
+	if routeOptions.GeoIP != nil || routeOptions.Geosite != nil {
+		report.Warn("route", "", "geoip and geosite resources are not exported")
+	}
+	if routeOptions.AutoDetectInterface || routeOptions.DefaultInterface != "" || routeOptions.DefaultMark != 0 {
+		report.Warn("route", "", "default interface and routing mark are not exported")
+	}
+	if len(routingConfig.Rules) == 0 {
+		return nil
+	}
+	return &routingConfig
+}
+
+// exportRule converts a rule into Xray field rules.
+// sing-box matches domain or IP items, Xray requires both, so a rule with both is split into two.
+func exportRule(rule option.Rule) ([]exportRuleConfig, error) {
+	if rule.Type == C.RuleTypeLogical {
+		return nil, E.New("logical rule is not supported")
+	}
+	defaultRule := rule.DefaultOptions
+	if field := unsupportedField(defaultRule,
+		"Inbound", "Network", "AuthUser", "Protocol",
+		"Domain", "DomainSuffix", "DomainKeyword", "DomainRegex", "Geosite",
+		"SourceGeoIP", "GeoIP", "SourceIPCIDR", "IPCIDR",
+		"SourcePort", "SourcePortRange", "Port", "PortRange", "Outbound",
+	); field != "" {
+		return nil, v2box.NewPathError(field, "rule item is not supported")
+	}
+	fieldRule := exportRuleConfig{
+		Type:        "field",
+		OutboundTag: defaultRule.Outbound,
+		Port:        exportPorts(defaultRule.Port, defaultRule.PortRange),
+		Network:     defaultRule.Network,
+		SourceIP:    exportAddresses(defaultRule.SourceGeoIP, defaultRule.SourceIPCIDR),
+		SourcePort:  exportPorts(defaultRule.SourcePort, defaultRule.SourcePortRange),
+		User:        defaultRule.AuthUser,
+		InboundTag:  defaultRule.Inbound,
+		Protocols:   defaultRule.Protocol,
+	}
+	domainRule := fieldRule
+	domainRule.Domain = exportDomains(defaultRule.Domain, defaultRule.DomainSuffix, defaultRule.DomainKeyword, defaultRule.DomainRegex, defaultRule.Geosite)
+	ipRule := fieldRule
+	ipRule.IP = exportAddresses(defaultRule.GeoIP, defaultRule.IPCIDR)
+	switch {
+	case len(domainRule.Domain) > 0 && len(ipRule.IP) > 0:
+		return []exportRuleConfig{domainRule, ipRule}, nil
+	case len(ipRule.IP) > 0:
+		return []exportRuleConfig{ipRule}, nil
+	default:
+		return []exportRuleConfig{domainRule}, nil
+	}
+}
+
+// unsupportedField returns the JSON name of the first set field of rule that is not listed in supported.
+func unsupportedField(rule any, supported ...string) string {
+	ruleValue := reflect.ValueOf(rule)
+	ruleType := ruleValue.Type()
+	for i := 0; i < ruleType.NumField(); i++ {
+		field := ruleType.Field(i)
+		if ruleValue.Field(i).IsZero() || common.Contains(supported, field.Name) {
+			continue
+		}
+		return strings.Split(field.Tag.Get("json"), ",")[0]
+	}
+	return ""
+}
+
+func exportDomains(domains []string, domainSuffixes []string, domainKeywords []string, domainRegexes []string, geosites []string) []string {
+	var domainList []string
+	// a domain together with its dotted suffix is the "domain:" matcher,
+	// other suffixes are plain string suffixes in sing-box.
+	for _, domain := range domains {
+		if common.Contains(domainSuffixes, "."+domain) {
+			continue
+		}
+		domainList = append(domainList, "full:"+domain)
+	}
+	for _, domainSuffix := range domainSuffixes {
+		if strings.HasPrefix(domainSuffix, ".") && common.Contains(domains, domainSuffix[1:]) {
+			domainList = append(domainList, "domain:"+domainSuffix[1:])
+		} else {
+			domainList = append(domainList, "regexp:"+regexp.QuoteMeta(domainSuffix)+"$")
+		}
+	}
+	for _, domainKeyword := range domainKeywords {
+		domainList = append(domainList, "keyword:"+domainKeyword)
+	}
+	for _, domainRegex := range domainRegexes {
+		domainList = append(domainList, "regexp:"+domainRegex)
+	}
+	for _, geosite := range geosites {
+		domainList = append(domainList, "geosite:"+geosite)
+	}
+	return domainList
+}
+
+func exportAddresses(geoIPs []string, ipCIDRs []string) []string {
+	var addressList []string
+	for _, geoIP := range geoIPs {
+		addressList = append(addressList, "geoip:"+geoIP)
+	}
+	return append(addressList, ipCIDRs...)
+}
+
+func exportPorts(ports []uint16, portRanges []string) string {
+	var portList []string
+	for _, port := range ports {
+		portList = append(portList, F.ToString(port))
+	}
+	for _, portRange := range portRanges {
+		from, to, _ := strings.Cut(portRange, ":")
+		if from == "" {
+			from = "1"
+		}
+		if to == "" {
+			to = "65535"
+		}
+		portList = append(portList, from+"-"+to)
+	}
+	return strings.Join(portList, ",")
+}
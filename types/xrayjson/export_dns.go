@@ -0,0 +1,170 @@
+package xrayjson
+
+import (
+	"net/url"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
+)
+
+type exportDNSConfig struct {
+	Servers       []exportNameServerConfig `json:"servers,omitempty"`
+	QueryStrategy string                   `json:"queryStrategy,omitempty"`
+	DisableCache  bool                     `json:"disableCache,omitempty"`
+}
+
+type exportNameServerConfig struct {
+	Address      string   `json:"address"`
+	Port         uint16   `json:"port,omitempty"`
+	SkipFallback bool     `json:"skipFallback,omitempty"`
+	Domains      []string `json:"domains,omitempty"`
+}
+
+// exportDNS converts DNS servers and domain rules into Xray name servers.
+// Rule domains are attached to the server of the rule, and the final server is moved to the front,
+// as Xray queries servers in order for unmatched domains.
+func exportDNS(dnsOptions option.DNSOptions, outbounds []option.Outbound, report *v2box.Report) *exportDNSConfig {
+	dnsConfig := exportDNSConfig{
+		QueryStrategy: exportQueryStrategy(dnsOptions.Strategy),
+		DisableCache:  dnsOptions.DisableCache,
+	}
+	if dnsOptions.DisableExpire {
+		report.Warn("dns.disable_expire", "", "disable expire is not exported")
+	}
+	finalServer := dnsOptions.Final
+	if finalServer == "" && len(dnsOptions.Servers) > 0 {
+		finalServer = dnsOptions.Servers[0].Tag
+	}
+	serverIndex := make(map[string]int)
+	for i, server := range dnsOptions.Servers {
+		path := v2box.IndexPath("dns.servers", i)
+		serverConfig, err := exportDNSServer(server, outbounds, path, report)
+		if err != nil {
+			report.Drop(path, server.Tag, err)
+			continue
+		}
+		if server.Tag != "" {
+			serverIndex[server.Tag] = len(dnsConfig.Servers)
+		}
+		dnsConfig.Servers = append(dnsConfig.Servers, serverConfig)
+	}
+	for i, rule := range dnsOptions.Rules {
+		path := v2box.IndexPath("dns.rules", i)
+		if rule.Type == C.RuleTypeLogical {
+			report.Drop(path, "", E.New("logical rule is not supported"))
+			continue
+		}
+		defaultRule := rule.DefaultOptions
+		if field := unsupportedField(defaultRule, "Domain", "DomainSuffix", "DomainKeyword", "DomainRegex", "Geosite", "Server", "DisableCache"); field != "" {
+			report.Drop(path, "", v2box.NewPathError(field, "only domain rules are supported by Xray DNS"))
+			continue
+		}
+		index, loaded := serverIndex[defaultRule.Server]
+		if !loaded {
+			report.Drop(path, "", v2box.NewPathError("server", "server ", defaultRule.Server, " is not exported"))
+			continue
+		}
+		if defaultRule.DisableCache {
+			report.Warn(path+".disable_cache", "", "disable cache is not exported")
+		}
+		serverConfig := &dnsConfig.Servers[index]
+		serverConfig.Domains = append(serverConfig.Domains, exportDomains(defaultRule.Domain, defaultRule.DomainSuffix, defaultRule.DomainKeyword, defaultRule.DomainRegex, defaultRule.Geosite)...)
+		if defaultRule.Server != finalServer {
+			serverConfig.SkipFallback = true
+		}
+	}
+	if finalServer != "" {
+		if finalIndex, loaded := serverIndex[finalServer]; !loaded {
+			report.Warn("dns.final", "", "final server ", finalServer, " is not exported")
+		} else if finalIndex > 0 {
+			finalConfig := dnsConfig.Servers[finalIndex]
+			copy(dnsConfig.Servers[1:finalIndex+1], dnsConfig.Servers[:finalIndex])
+			dnsConfig.Servers[0] = finalConfig
+		}
+	}
+	return &dnsConfig
+}
+
+func exportDNSServer(server option.DNSServerOptions, outbounds []option.Outbound, path string, report *v2box.Report) (exportNameServerConfig, error) {
+	var serverConfig exportNameServerConfig
+	if server.Strategy != 0 {
+		report.Warn(path+".strategy", server.Tag, "server strategy is not exported")
+	}
+	if server.AddressResolver != "" || server.AddressStrategy != 0 {
+		report.Warn(path+".address_resolver", server.Tag, "address resolver is not exported")
+	}
+	// Xray sends DNS queries through the routing, "+local" servers are queried directly.
+	localDetour := common.Any(outbounds, func(it option.Outbound) bool {
+		return it.Tag == server.Detour && it.Type == C.TypeDirect
+	})
+	detourExported := server.Detour == ""
+	if server.Address == "local" {
+		serverConfig.Address = "localhost"
+		return serverConfig, nil
+	}
+	serverURL := &url.URL{Scheme: "udp", Host: server.Address}
+	if strings.Contains(server.Address, "://") {
+		var err error
+		serverURL, err = url.Parse(server.Address)
+		if err != nil {
+			return exportNameServerConfig{}, v2box.WrapPathError("address", err)
+		}
+	}
+	switch serverURL.Scheme {
+	case "udp":
+		exportUDPServer(serverURL.Host, &serverConfig)
+	case "tcp":
+		serverConfig.Address = "tcp://" + serverURL.Host
+		if localDetour {
+			serverConfig.Address = "tcp+local://" + serverURL.Host
+			detourExported = true
+		}
+	case "https":
+		if localDetour {
+			serverURL.Scheme = "https+local"
+			detourExported = true
+		}
+		serverConfig.Address = serverURL.String()
+	case "quic":
+		serverConfig.Address = "quic+local://" + serverURL.Host
+		if !localDetour {
+			report.Warn(path+".address", server.Tag, "DNS over QUIC is queried directly by Xray")
+		}
+		detourExported = true
+	case "tls":
+		return exportNameServerConfig{}, v2box.NewPathError("address", "DNS over TLS is not supported by Xray")
+	case "h3":
+		return exportNameServerConfig{}, v2box.NewPathError("address", "DNS over HTTP/3 is not supported by Xray")
+	default:
+		return exportNameServerConfig{}, v2box.NewPathError("address", serverURL.Scheme, " server is not supported by Xray")
+	}
+	if !detourExported {
+		report.Warn(path+".detour", server.Tag, "detour is not exported, queries to ", server.Address, " follow the routing")
+	}
+	return serverConfig, nil
+}
+
+func exportUDPServer(address string, serverConfig *exportNameServerConfig) {
+	destination := M.ParseSocksaddr(address)
+	serverConfig.Address = destination.AddrString()
+	if destination.Port != 0 && destination.Port != 53 {
+		serverConfig.Port = destination.Port
+	}
+}
+
+func exportQueryStrategy(domainStrategy option.DomainStrategy) string {
+	switch dns.DomainStrategy(domainStrategy) {
+	case dns.DomainStrategyUseIPv4:
+		return "UseIPv4"
+	case dns.DomainStrategyUseIPv6:
+		return "UseIPv6"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,220 @@
+package xrayjson
+
+import (
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
+)
+
+type exportStreamConfig struct {
+	Network         string               `json:"network,omitempty"`
+	Security        string               `json:"security,omitempty"`
+	TLSSettings     *exportTLSConfig     `json:"tlsSettings,omitempty"`
+	REALITYSettings *exportREALITYConfig `json:"realitySettings,omitempty"`
+	WSSettings      map[string]any       `json:"wsSettings,omitempty"`
+	HTTPSettings    map[string]any       `json:"httpSettings,omitempty"`
+	GRPCSettings    map[string]any       `json:"grpcSettings,omitempty"`
+	SocketSettings  *exportSocketConfig  `json:"sockopt,omitempty"`
+}
+
+type exportSocketConfig struct {
+	Mark                int    `json:"mark,omitempty"`
+	TCPFastOpen         bool   `json:"tcpFastOpen,omitempty"`
+	TProxy              string `json:"tproxy,omitempty"`
+	AcceptProxyProtocol bool   `json:"acceptProxyProtocol,omitempty"`
+	DomainStrategy      string `json:"domainStrategy,omitempty"`
+	DialerProxy         string `json:"dialerProxy,omitempty"`
+	Interface           string `json:"interface,omitempty"`
+}
+
+type exportTLSConfig struct {
+	ServerName   string                    `json:"serverName,omitempty"`
+	Insecure     bool                      `json:"allowInsecure,omitempty"`
+	ALPN         []string                  `json:"alpn,omitempty"`
+	MinVersion   string                    `json:"minVersion,omitempty"`
+	MaxVersion   string                    `json:"maxVersion,omitempty"`
+	CipherSuites string                    `json:"cipherSuites,omitempty"`
+	Fingerprint  string                    `json:"fingerprint,omitempty"`
+	Certificates []exportCertificateConfig `json:"certificates,omitempty"`
+}
+
+type exportCertificateConfig struct {
+	CertificateFile string   `json:"certificateFile,omitempty"`
+	Certificate     []string `json:"certificate,omitempty"`
+	KeyFile         string   `json:"keyFile,omitempty"`
+	Key             []string `json:"key,omitempty"`
+	Usage           string   `json:"usage,omitempty"`
+}
+
+type exportREALITYConfig struct {
+	Dest        string   `json:"dest,omitempty"`
+	ServerNames []string `json:"serverNames,omitempty"`
+	PrivateKey  string   `json:"privateKey,omitempty"`
+	MaxTimeDiff uint64   `json:"maxTimeDiff,omitempty"`
+	ShortIds    []string `json:"shortIds,omitempty"`
+	Fingerprint string   `json:"fingerprint,omitempty"`
+	ServerName  string   `json:"serverName,omitempty"`
+	PublicKey   string   `json:"publicKey,omitempty"`
+	ShortId     string   `json:"shortId,omitempty"`
+}
+
+func (c *exportStreamConfig) socket() *exportSocketConfig {
+	if c.SocketSettings == nil {
+		c.SocketSettings = &exportSocketConfig{}
+	}
+	return c.SocketSettings
+}
+
+func (c *exportStreamConfig) isEmpty() bool {
+	return c.Network == "" && c.Security == "" && c.SocketSettings == nil
+}
+
+func exportTransport(transportOptions option.V2RayTransportOptions, streamConfig *exportStreamConfig, path string, tag string, report *v2box.Report) {
+	switch transportOptions.Type {
+	case C.V2RayTransportTypeHTTP:
+		streamConfig.Network = "http"
+		httpSettings := make(map[string]any)
+		if len(transportOptions.HTTPOptions.Host) > 0 {
+			httpSettings["host"] = transportOptions.HTTPOptions.Host
+		}
+		if transportOptions.HTTPOptions.Path != "" {
+			httpSettings["path"] = transportOptions.HTTPOptions.Path
+		}
+		if transportOptions.HTTPOptions.Method != "" {
+			httpSettings["method"] = transportOptions.HTTPOptions.Method
+		}
+		if len(transportOptions.HTTPOptions.Headers) > 0 {
+			httpSettings["headers"] = transportOptions.HTTPOptions.Headers
+		}
+		streamConfig.HTTPSettings = httpSettings
+	case C.V2RayTransportTypeWebsocket:
+		streamConfig.Network = "ws"
+		wsSettings := make(map[string]any)
+		wsPath := transportOptions.WebsocketOptions.Path
+		if transportOptions.WebsocketOptions.MaxEarlyData > 0 {
+			// Xray only sends early data in the Sec-WebSocket-Protocol header, configured by the "ed" query of the path.
+			if transportOptions.WebsocketOptions.EarlyDataHeaderName == "Sec-WebSocket-Protocol" {
+				separator := "?"
+				if strings.Contains(wsPath, "?") {
+					separator = "&"
+				}
+				wsPath = F.ToString(wsPath, separator, "ed=", transportOptions.WebsocketOptions.MaxEarlyData)
+			} else {
+				report.Warn(path+".max_early_data", tag, "early data without the Sec-WebSocket-Protocol header is not exported")
+			}
+		}
+		if wsPath != "" {
+			wsSettings["path"] = wsPath
+		}
+		if len(transportOptions.WebsocketOptions.Headers) > 0 {
+			wsSettings["headers"] = transportOptions.WebsocketOptions.Headers
+		}
+		streamConfig.WSSettings = wsSettings
+	case C.V2RayTransportTypeQUIC:
+		streamConfig.Network = "quic"
+	case C.V2RayTransportTypeGRPC:
+		streamConfig.Network = "grpc"
+		streamConfig.GRPCSettings = map[string]any{
+			"serviceName": transportOptions.GRPCOptions.ServiceName,
+		}
+	}
+}
+
+func exportInboundTLS(tlsOptions option.InboundTLSOptions, streamConfig *exportStreamConfig) error {
+	if tlsOptions.ACME != nil && len(tlsOptions.ACME.Domain) > 0 {
+		return v2box.NewPathError("acme", "ACME is not supported by Xray")
+	}
+	if realityOptions := tlsOptions.Reality; realityOptions != nil && realityOptions.Enabled {
+		realityConfig := exportREALITYConfig{
+			Dest:        M.ParseSocksaddrHostPort(realityOptions.Handshake.Server, realityOptions.Handshake.ServerPort).String(),
+			PrivateKey:  realityOptions.PrivateKey,
+			MaxTimeDiff: uint64(time.Duration(realityOptions.MaxTimeDifference) / time.Millisecond),
+			ShortIds:    realityOptions.ShortID,
+		}
+		if tlsOptions.ServerName != "" {
+			realityConfig.ServerNames = []string{tlsOptions.ServerName}
+		}
+		streamConfig.Security = "reality"
+		streamConfig.REALITYSettings = &realityConfig
+		return nil
+	}
+	tlsConfig := exportTLSConfig{
+		ServerName:   tlsOptions.ServerName,
+		ALPN:         tlsOptions.ALPN,
+		MinVersion:   tlsOptions.MinVersion,
+		MaxVersion:   tlsOptions.MaxVersion,
+		CipherSuites: strings.Join(tlsOptions.CipherSuites, ":"),
+	}
+	certificate := exportCertificateConfig{
+		CertificateFile: tlsOptions.CertificatePath,
+		KeyFile:         tlsOptions.KeyPath,
+	}
+	if tlsOptions.Certificate != "" {
+		certificate.Certificate = strings.Split(tlsOptions.Certificate, "\n")
+	}
+	if tlsOptions.Key != "" {
+		certificate.Key = strings.Split(tlsOptions.Key, "\n")
+	}
+	if certificate.CertificateFile != "" || len(certificate.Certificate) > 0 {
+		tlsConfig.Certificates = []exportCertificateConfig{certificate}
+	}
+	streamConfig.Security = "tls"
+	streamConfig.TLSSettings = &tlsConfig
+	return nil
+}
+
+func exportOutboundTLS(tlsOptions option.OutboundTLSOptions, streamConfig *exportStreamConfig, path string, tag string, report *v2box.Report) {
+	if tlsOptions.ECH != nil && tlsOptions.ECH.Enabled {
+		report.Warn(path+".ech", tag, "ECH is not exported")
+	}
+	if tlsOptions.DisableSNI {
+		report.Warn(path+".disable_sni", tag, "disable SNI is not exported")
+	}
+	var fingerprint string
+	if tlsOptions.UTLS != nil && tlsOptions.UTLS.Enabled {
+		fingerprint = tlsOptions.UTLS.Fingerprint
+		if fingerprint == "" {
+			fingerprint = "chrome"
+		}
+	}
+	if realityOptions := tlsOptions.Reality; realityOptions != nil && realityOptions.Enabled {
+		if fingerprint == "" {
+			report.Info(path+".utls", tag, "REALITY requires a fingerprint, chrome is used")
+			fingerprint = "chrome"
+		}
+		streamConfig.Security = "reality"
+		streamConfig.REALITYSettings = &exportREALITYConfig{
+			Fingerprint: fingerprint,
+			ServerName:  tlsOptions.ServerName,
+			PublicKey:   realityOptions.PublicKey,
+			ShortId:     realityOptions.ShortID,
+		}
+		return
+	}
+	tlsConfig := exportTLSConfig{
+		ServerName:   tlsOptions.ServerName,
+		Insecure:     tlsOptions.Insecure,
+		ALPN:         tlsOptions.ALPN,
+		MinVersion:   tlsOptions.MinVersion,
+		MaxVersion:   tlsOptions.MaxVersion,
+		CipherSuites: strings.Join(tlsOptions.CipherSuites, ":"),
+		Fingerprint:  fingerprint,
+	}
+	certificate := exportCertificateConfig{
+		CertificateFile: tlsOptions.CertificatePath,
+		Usage:           "verify",
+	}
+	if tlsOptions.Certificate != "" {
+		certificate.Certificate = strings.Split(tlsOptions.Certificate, "\n")
+	}
+	if certificate.CertificateFile != "" || len(certificate.Certificate) > 0 {
+		tlsConfig.Certificates = []exportCertificateConfig{certificate}
+	}
+	streamConfig.Security = "tls"
+	streamConfig.TLSSettings = &tlsConfig
+}
@@ -0,0 +1,29 @@
+package xrayjson
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/v2box"
+
+	"github.com/xtls/xray-core/app/dns/fakedns"
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+var errFakeDNSServer = E.New("fakedns is not supported, domains of the server are resolved by other servers with real addresses")
+
+// reportFakeDNS reports FakeDNS pools, the sing-box version migrated to has no FakeIP.
+// DNS queries are answered with real addresses instead, transparent proxies have to sniff the destination domain.
+func reportFakeDNS(fakeDNSConfig *conf.FakeDNSConfig, path string, report *v2box.Report) {
+	pools, err := fakeDNSConfig.Build()
+	if err != nil {
+		report.Drop(path, "", err)
+		return
+	}
+	poolRanges := common.Map(pools.Pools, func(it *fakedns.FakeDnsPool) string {
+		return F.ToString(it.IpPool, " (size ", it.LruSize, ")")
+	})
+	report.Drop(path, "", E.New("FakeIP is not supported, pools ", strings.Join(poolRanges, ", "), " are not migrated"))
+}
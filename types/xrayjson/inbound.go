@@ -12,6 +12,7 @@ import (
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/v2box"
 
 	"github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/infra/conf"
@@ -30,7 +31,7 @@ import (
 //go:linkname inboundConfigLoader github.com/xtls/xray-core/infra/conf.inboundConfigLoader
 var inboundConfigLoader *conf.JSONConfigLoader
 
-func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, error) {
+func migrateInbound(inboundConfig conf.InboundDetourConfig, inboundTags v2box.TagSet, maxPorts int, path string, report *v2box.Report) ([]option.Inbound, error) {
 	var inbound option.Inbound
 	inbound.Tag = inboundConfig.Tag
 
@@ -38,9 +39,7 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 	if inboundConfig.ListenOn != nil {
 		listenOptions.Listen = option.NewListenAddress(M.ParseAddr(inboundConfig.ListenOn.Address.String()))
 	}
-	if inboundConfig.PortList != nil {
-		listenOptions.ListenPort = parsePort(inboundConfig.PortList)
-	}
+	listenOptions.InboundOptions = parseSniffing(inboundConfig.SniffingConfig, path+".sniffing", inbound.Tag, report)
 
 	var tlsOptions option.InboundTLSOptions
 	var transportOptions option.V2RayTransportOptions
@@ -59,6 +58,9 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 				}
 			}
 			tproxyName = socketSettings.TProxy
+			if socketSettings.DomainStrategy != "" && !strings.EqualFold(socketSettings.DomainStrategy, "AsIs") {
+				report.Info(path+".streamSettings.sockopt.domainStrategy", inbound.Tag, "domain strategy only applies to dialing and is not migrated")
+			}
 			if socketSettings.AcceptProxyProtocol {
 				listenOptions.ProxyProtocol = true
 				listenOptions.ProxyProtocolAcceptNoHeader = true
@@ -66,31 +68,13 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 		}
 		transportOptions, err = parseTransport(streamSettings)
 		if err != nil {
-			return option.Inbound{}, err
+			return nil, err
 		}
 		if security := streamSettings.Security; security != "" {
 			switch security {
+			case "none":
 			case "tls":
-				tlsOptions.Enabled = true
-				if tlsSettings := streamSettings.TLSSettings; tlsSettings != nil {
-					tlsOptions.ServerName = tlsSettings.ServerName
-					for _, certConfig := range tlsSettings.Certs {
-						if certConfig.Usage != "" && certConfig.Usage != "encipherment" {
-							continue
-						}
-						if len(certConfig.CertStr) > 0 {
-							tlsOptions.Certificate = strings.Join(certConfig.CertStr, "\n")
-						}
-						if len(certConfig.KeyStr) > 0 {
-							tlsOptions.Key = strings.Join(certConfig.KeyStr, "\n")
-						}
-						tlsOptions.CertificatePath = certConfig.CertFile
-						tlsOptions.KeyPath = certConfig.KeyFile
-					}
-					if tlsSettings.ALPN != nil && tlsSettings.ALPN.Len() > 0 {
-						tlsOptions.ALPN = []string(*tlsSettings.ALPN)
-					}
-				}
+				tlsOptions = parseInboundTLS(streamSettings.TLSSettings, path+".streamSettings.tlsSettings", inbound.Tag, report)
 			case "reality":
 				tlsOptions.Enabled = true
 				if tlsSettings := streamSettings.REALITYSettings; tlsSettings != nil {
@@ -117,7 +101,12 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 							}
 						}
 					}
+					if len(tlsSettings.ServerNames) > 1 {
+						report.Warn(path+".streamSettings.realitySettings.serverNames", inbound.Tag, "only the first of ", len(tlsSettings.ServerNames), " server names is migrated")
+					}
 				}
+			default:
+				report.Warn(path+".streamSettings.security", inbound.Tag, "unsupported security ", security, " is not migrated")
 			}
 		}
 	}
@@ -127,11 +116,11 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 	}
 	rawConfig, err := inboundConfigLoader.LoadWithID(settingsString, inboundConfig.Protocol)
 	if err != nil {
-		return option.Inbound{}, err
+		return nil, err
 	}
 	proxySettings, err := rawConfig.(conf.Buildable).Build()
 	if err != nil {
-		return option.Inbound{}, err
+		return nil, err
 	}
 	switch proxyType := proxySettings.(type) {
 	case *dokodemo.Config:
@@ -154,14 +143,14 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 	case *http.ServerConfig:
 		inbound.Type = C.TypeHTTP
 		inbound.HTTPOptions.ListenOptions = listenOptions
+		if tlsOptions.Enabled {
+			inbound.HTTPOptions.TLS = &tlsOptions
+		}
 		for username, password := range proxyType.Accounts {
 			inbound.HTTPOptions.Users = append(inbound.HTTPOptions.Users, auth.User{
 				Username: username,
 				Password: password,
 			})
-			if tlsOptions.Enabled {
-				inbound.HTTPOptions.TLS = &tlsOptions
-			}
 		}
 	case *socks.ServerConfig:
 		inbound.Type = C.TypeSocks
@@ -179,17 +168,21 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 		for _, user := range proxyType.Users {
 			shadowsocksAccount, err := user.Account.GetInstance()
 			if err != nil {
-				return option.Inbound{}, E.Cause(err, "create account")
+				return nil, E.Cause(err, "create account")
 			}
 			switch shadowsocksAccountType := shadowsocksAccount.(type) {
 			case *shadowsocks.Account:
 				switch shadowsocksAccountType.CipherType {
 				case shadowsocks.CipherType_AES_128_GCM:
 					inbound.ShadowsocksOptions.Method = "aes-128-gcm"
+				case shadowsocks.CipherType_AES_256_GCM:
+					inbound.ShadowsocksOptions.Method = "aes-256-gcm"
 				case shadowsocks.CipherType_CHACHA20_POLY1305:
 					inbound.ShadowsocksOptions.Method = "chacha20-ietf-poly1305"
-				default:
+				case shadowsocks.CipherType_NONE:
 					inbound.ShadowsocksOptions.Method = "none"
+				default:
+					return nil, v2box.NewPathError("settings.method", "unsupported shadowsocks cipher: ", shadowsocksAccountType.CipherType)
 				}
 				inbound.ShadowsocksOptions.Password = shadowsocksAccountType.Password
 				break
@@ -241,7 +234,7 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 		for _, user := range proxyType.User {
 			account, err := user.Account.GetInstance()
 			if err != nil {
-				return option.Inbound{}, E.Cause(err, "get instance of ", user.Account.Type)
+				return nil, E.Cause(err, "get instance of ", user.Account.Type)
 			}
 			switch accountType := account.(type) {
 			case *vmess.Account:
@@ -264,7 +257,7 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 		for _, client := range proxyType.Clients {
 			account, err := client.Account.GetInstance()
 			if err != nil {
-				return option.Inbound{}, E.Cause(err, "get instance of ", client.Account.Type)
+				return nil, E.Cause(err, "get instance of ", client.Account.Type)
 			}
 			switch accountType := account.(type) {
 			case *vless.Account:
@@ -287,7 +280,7 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 		for _, user := range proxyType.Users {
 			account, err := user.Account.GetInstance()
 			if err != nil {
-				return option.Inbound{}, E.Cause(err, "get instance of ", user.Account.Type)
+				return nil, E.Cause(err, "get instance of ", user.Account.Type)
 			}
 			switch accountType := account.(type) {
 			case *trojan.Account:
@@ -298,7 +291,22 @@ func migrateInbound(inboundConfig conf.InboundDetourConfig) (option.Inbound, err
 			}
 		}
 	default:
-		return option.Inbound{}, E.New("unsupported inbound type ", reflect.TypeOf(proxyType))
+		return nil, v2box.NewPathError("protocol", "unsupported inbound type ", reflect.TypeOf(proxyType))
+	}
+	switch inbound.Type {
+	case C.TypeVMess, C.TypeVLESS, C.TypeTrojan:
+	default:
+		if transportOptions.Type != "" {
+			report.Warn(path+".streamSettings.network", inbound.Tag, "transport is not supported by ", inbound.Type, " inbound")
+		}
+		if tlsOptions.Enabled && inbound.Type != C.TypeHTTP {
+			report.Warn(path+".streamSettings.security", inbound.Tag, "TLS is not supported by ", inbound.Type, " inbound")
+		}
+	}
+	if inboundConfig.PortList != nil {
+		ports, portDescription := listPorts(inboundConfig.PortList)
+		ports = allocatePorts(inboundConfig.Allocation, ports, maxPorts, path+".allocate", inbound.Tag, report)
+		return migratePorts(inbound, ports, portDescription, inboundTags, maxPorts, path+".port", report), nil
 	}
-	return inbound, nil
+	return []option.Inbound{inbound}, nil
 }
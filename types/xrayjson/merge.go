@@ -0,0 +1,109 @@
+package xrayjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	sjson "github.com/sagernet/sing-box/common/json"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/v2box"
+)
+
+// Merge merges files with the Xray rules: later files replace top-level sections.
+// A file with a single inbound or outbound updates the one with the same tag,
+// or is added to the list, outbounds are prepended unless the file name contains "tail".
+// Any other inbound or outbound list replaces the current one.
+func Merge(files []v2box.ConfigFile) ([]byte, error) {
+	config := make(map[string]json.RawMessage)
+	var inbounds, outbounds []json.RawMessage
+	for _, file := range files {
+		var override map[string]json.RawMessage
+		decoder := json.NewDecoder(sjson.NewCommentFilter(bytes.NewReader(file.Content)))
+		err := decoder.Decode(&override)
+		if err != nil {
+			return nil, E.Cause(err, "decode ", file.Name)
+		}
+		for key, value := range override {
+			if string(value) == "null" {
+				continue
+			}
+			switch key {
+			case "inbounds":
+				var overrideInbounds []json.RawMessage
+				err = json.Unmarshal(value, &overrideInbounds)
+				if err != nil {
+					return nil, E.Cause(err, "decode inbounds of ", file.Name)
+				}
+				inbounds, err = mergeDetours(inbounds, overrideInbounds, false)
+				if err != nil {
+					return nil, E.Cause(err, "merge inbounds of ", file.Name)
+				}
+			case "outbounds":
+				var overrideOutbounds []json.RawMessage
+				err = json.Unmarshal(value, &overrideOutbounds)
+				if err != nil {
+					return nil, E.Cause(err, "decode outbounds of ", file.Name)
+				}
+				outbounds, err = mergeDetours(outbounds, overrideOutbounds, !strings.Contains(strings.ToLower(file.Name), "tail"))
+				if err != nil {
+					return nil, E.Cause(err, "merge outbounds of ", file.Name)
+				}
+			default:
+				config[key] = value
+			}
+		}
+	}
+	var err error
+	if len(inbounds) > 0 {
+		config["inbounds"], err = json.Marshal(inbounds)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(outbounds) > 0 {
+		config["outbounds"], err = json.Marshal(outbounds)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(config)
+}
+
+func mergeDetours(detours []json.RawMessage, overrideDetours []json.RawMessage, prepend bool) ([]json.RawMessage, error) {
+	if len(overrideDetours) == 0 {
+		return detours, nil
+	}
+	if len(detours) == 0 || len(overrideDetours) > 1 {
+		return overrideDetours, nil
+	}
+	overrideTag, err := detourTag(overrideDetours[0])
+	if err != nil {
+		return nil, err
+	}
+	for i, detour := range detours {
+		tag, err := detourTag(detour)
+		if err != nil {
+			return nil, err
+		}
+		if tag == overrideTag {
+			detours[i] = overrideDetours[0]
+			return detours, nil
+		}
+	}
+	if prepend {
+		return append(overrideDetours, detours...), nil
+	}
+	return append(detours, overrideDetours[0]), nil
+}
+
+func detourTag(detour json.RawMessage) (string, error) {
+	var tagged struct {
+		Tag string `json:"tag"`
+	}
+	err := json.Unmarshal(detour, &tagged)
+	if err != nil {
+		return "", err
+	}
+	return tagged.Tag, nil
+}
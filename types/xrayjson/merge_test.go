@@ -0,0 +1,114 @@
+package xrayjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sagernet/v2box"
+)
+
+func TestMerge(t *testing.T) {
+	for _, testCase := range []struct {
+		name      string
+		files     []v2box.ConfigFile
+		inbounds  []string
+		outbounds []string
+		log       string
+	}{
+		{
+			name: "update by tag",
+			files: []v2box.ConfigFile{
+				{Name: "1.json", Content: []byte(`{"outbounds": [{"tag": "a"}, {"tag": "b"}]}`)},
+				{Name: "2.json", Content: []byte(`{"outbounds": [{"tag": "b", "protocol": "freedom"}]}`)},
+			},
+			outbounds: []string{"a", "b:freedom"},
+		},
+		{
+			name: "prepend outbound",
+			files: []v2box.ConfigFile{
+				{Name: "1.json", Content: []byte(`{"outbounds": [{"tag": "a"}]}`)},
+				{Name: "2.json", Content: []byte(`{"outbounds": [{"tag": "b"}]}`)},
+			},
+			outbounds: []string{"b", "a"},
+		},
+		{
+			name: "append tail outbound",
+			files: []v2box.ConfigFile{
+				{Name: "1.json", Content: []byte(`{"outbounds": [{"tag": "a"}]}`)},
+				{Name: "2_Tail.json", Content: []byte(`{"outbounds": [{"tag": "b"}]}`)},
+			},
+			outbounds: []string{"a", "b"},
+		},
+		{
+			name: "append inbound",
+			files: []v2box.ConfigFile{
+				{Name: "1.json", Content: []byte(`{"inbounds": [{"tag": "a"}]}`)},
+				{Name: "2.json", Content: []byte(`{"inbounds": [{"tag": "b"}]}`)},
+			},
+			inbounds: []string{"a", "b"},
+		},
+		{
+			name: "replace list",
+			files: []v2box.ConfigFile{
+				{Name: "1.json", Content: []byte(`{"inbounds": [{"tag": "a"}], "outbounds": [{"tag": "a"}]}`)},
+				{Name: "2.json", Content: []byte(`{"inbounds": [{"tag": "b"}, {"tag": "c"}], "outbounds": [{"tag": "a", "protocol": "freedom"}, {"tag": "c"}]}`)},
+			},
+			inbounds:  []string{"b", "c"},
+			outbounds: []string{"a:freedom", "c"},
+		},
+		{
+			name: "file order",
+			files: []v2box.ConfigFile{
+				{Name: "1.json", Content: []byte(`{"log": {"loglevel": "debug"}, "outbounds": [{"tag": "a"}]}`)},
+				{Name: "2.json", Content: []byte(`{"log": {"loglevel": "error"}, "outbounds": null}`)},
+			},
+			outbounds: []string{"a"},
+			log:       "error",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			content, err := Merge(testCase.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var config struct {
+				Log *struct {
+					LogLevel string `json:"loglevel"`
+				} `json:"log"`
+				Inbounds  []mergeTestDetour `json:"inbounds"`
+				Outbounds []mergeTestDetour `json:"outbounds"`
+			}
+			err = json.Unmarshal(content, &config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if inbounds := mergeTestDetours(config.Inbounds); !reflect.DeepEqual(inbounds, testCase.inbounds) {
+				t.Errorf("expected inbounds %v, got %v", testCase.inbounds, inbounds)
+			}
+			if outbounds := mergeTestDetours(config.Outbounds); !reflect.DeepEqual(outbounds, testCase.outbounds) {
+				t.Errorf("expected outbounds %v, got %v", testCase.outbounds, outbounds)
+			}
+			if config.Log != nil && config.Log.LogLevel != testCase.log {
+				t.Errorf("expected log level %s, got %s", testCase.log, config.Log.LogLevel)
+			}
+		})
+	}
+}
+
+type mergeTestDetour struct {
+	Tag      string `json:"tag"`
+	Protocol string `json:"protocol"`
+}
+
+func mergeTestDetours(detours []mergeTestDetour) []string {
+	var tags []string
+	for _, detour := range detours {
+		if detour.Protocol != "" {
+			tags = append(tags, detour.Tag+":"+detour.Protocol)
+		} else {
+			tags = append(tags, detour.Tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,89 @@
+package xrayjson
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/json"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/v2box"
+
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+var errMux = E.New("mux is not supported, as sing-box multiplex is not compatible with mux.cool servers, connections are not multiplexed")
+
+type xudpConfig struct {
+	Concurrency int16  `json:"xudpConcurrency"`
+	ProxyUDP443 string `json:"xudpProxyUDP443"`
+}
+
+// parseXUDP decodes the XUDP options of outbound mux settings by outbound index, as MuxConfig does not support them.
+func parseXUDP(content []byte) ([]xudpConfig, error) {
+	var config struct {
+		Outbounds []struct {
+			Mux xudpConfig `json:"mux"`
+		} `json:"outbounds"`
+	}
+	decoder := json.NewDecoder(json.NewCommentFilter(bytes.NewReader(content)))
+	err := decoder.Decode(&config)
+	if err != nil {
+		return nil, err
+	}
+	xudpConfigs := make([]xudpConfig, len(config.Outbounds))
+	for i, outbound := range config.Outbounds {
+		xudpConfigs[i] = outbound.Mux
+	}
+	return xudpConfigs, nil
+}
+
+// migrateMultiplex reports enabled mux settings of the outbound as dropped.
+// Multiplex is left disabled, since enabling it would break connections to mux.cool servers.
+// VLESS UDP is sent with the XUDP packet encoding instead.
+func migrateMultiplex(outbound *option.Outbound, muxSettings *conf.MuxConfig, xudpSettings xudpConfig, path string, report *v2box.Report) {
+	if muxSettings == nil || !muxSettings.Enabled {
+		return
+	}
+	if outbound.Type == C.TypeVLESS {
+		migrateXUDP(outbound, muxSettings, xudpSettings, path, report)
+		return
+	}
+	if muxSettings.Concurrency < 0 {
+		if xudpSettings.Concurrency > 0 {
+			report.Warn(path+".xudpConcurrency", outbound.Tag, "XUDP is not supported by ", outbound.Type, " outbound, UDP is sent with the ", outbound.Type, " protocol")
+		}
+		return
+	}
+	report.Drop(path, outbound.Tag, errMux)
+}
+
+// migrateXUDP sets the packet encoding of a VLESS outbound to XUDP if mux sends its UDP through XUDP,
+// which is the case unless xudpConcurrency, or concurrency if it is not set, is negative.
+func migrateXUDP(outbound *option.Outbound, muxSettings *conf.MuxConfig, xudpSettings xudpConfig, path string, report *v2box.Report) {
+	if muxSettings.Concurrency >= 0 {
+		report.Warn(path+".concurrency", outbound.Tag, "multiplex is not supported by vless outbound, TCP connections are not multiplexed")
+	}
+	xudpConcurrency := xudpSettings.Concurrency
+	if xudpConcurrency == 0 {
+		xudpConcurrency = muxSettings.Concurrency
+	}
+	if xudpConcurrency < 0 {
+		packetEncoding := ""
+		outbound.VLESSOptions.PacketEncoding = &packetEncoding
+		return
+	}
+	packetEncoding := "xudp"
+	outbound.VLESSOptions.PacketEncoding = &packetEncoding
+	if xudpConcurrency > 0 {
+		report.Warn(path+".xudpConcurrency", outbound.Tag, "UDP sessions are not multiplexed up to ", xudpConcurrency, " per connection, every UDP session uses its own XUDP connection")
+	}
+	reportProxyUDP443(xudpSettings, outbound.Tag, path, report)
+}
+
+func reportProxyUDP443(xudpSettings xudpConfig, tag string, path string, report *v2box.Report) {
+	if proxyUDP443 := strings.ToLower(xudpSettings.ProxyUDP443); proxyUDP443 != "" && proxyUDP443 != "allow" {
+		report.Warn(path+".xudpProxyUDP443", tag, "UDP 443 is sent through XUDP, ", xudpSettings.ProxyUDP443, " is not migrated")
+	}
+}
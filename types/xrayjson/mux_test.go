@@ -0,0 +1,37 @@
+package xrayjson
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/v2box"
+)
+
+func TestMigrateMultiplex(t *testing.T) {
+	options, report, err := Migrate([]byte(`{
+		"outbounds": [
+			{"protocol": "vmess", "tag": "vmess", "settings": {"vnext": [{"address": "1.2.3.4", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811"}]}]}, "mux": {"enabled": true, "concurrency": 4}},
+			{"protocol": "vless", "tag": "vless", "settings": {"vnext": [{"address": "1.2.3.4", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "encryption": "none"}]}]}, "mux": {"enabled": true, "concurrency": 8}}
+		]
+	}`), v2box.MigrateOptions{}, log.StdLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options.Outbounds) != 2 || options.Outbounds[0].Type != C.TypeVMess || options.Outbounds[1].Type != C.TypeVLESS {
+		t.Fatalf("unexpected outbounds %v", options.Outbounds)
+	}
+	if options.Outbounds[0].VMessOptions.Multiplex != nil {
+		t.Error("expected multiplex to be disabled for mux.cool servers")
+	}
+	var errorPaths []string
+	for _, entry := range report.Filter(v2box.SeverityError) {
+		errorPaths = append(errorPaths, entry.Path)
+	}
+	if len(errorPaths) != 1 || errorPaths[0] != "outbounds[0].mux" {
+		t.Errorf("expected outbounds[0].mux to be dropped, got errors at %v", errorPaths)
+	}
+	if packetEncoding := options.Outbounds[1].VLESSOptions.PacketEncoding; packetEncoding == nil || *packetEncoding != "xudp" {
+		t.Error("expected the xudp packet encoding for vless mux")
+	}
+}
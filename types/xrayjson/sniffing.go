@@ -0,0 +1,55 @@
+package xrayjson
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/v2box"
+
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// parseSniffing converts inbound sniffing into sing-box sniff options.
+// sing-box sniffs all protocols it supports and overrides the destination with any sniffed domain,
+// so destOverride only decides whether the destination is overridden, and routeOnly disables the override.
+func parseSniffing(sniffingConfig *conf.SniffingConfig, path string, tag string, report *v2box.Report) option.InboundOptions {
+	if sniffingConfig == nil || !sniffingConfig.Enabled {
+		return option.InboundOptions{}
+	}
+	sniffingSettings, err := sniffingConfig.Build()
+	if err != nil {
+		report.Drop(path, tag, err)
+		return option.InboundOptions{}
+	}
+	if sniffingConfig.MetadataOnly {
+		report.Warn(path+".metadataOnly", tag, "metadata only sniffing relies on fakedns, which is not supported, sniffing is not migrated")
+		return option.InboundOptions{}
+	}
+	var overrideProtocols []string
+	for _, protocol := range sniffingSettings.DestinationOverride {
+		switch protocol {
+		case "fakedns", "fakedns+others":
+			report.Warn(path+".destOverride", tag, "fakedns is not supported, destinations are not restored from fake addresses")
+			if protocol == "fakedns+others" {
+				overrideProtocols = append(overrideProtocols, "http", "tls", "quic")
+			}
+		default:
+			overrideProtocols = append(overrideProtocols, protocol)
+		}
+	}
+	overrideProtocols = common.Uniq(overrideProtocols)
+	overrideDestination := len(overrideProtocols) > 0 && !sniffingConfig.RouteOnly
+	if overrideDestination {
+		if len(overrideProtocols) < 3 {
+			report.Info(path+".destOverride", tag, "destination is overridden by domains of all sniffed protocols, not only ", strings.Join(overrideProtocols, ", "))
+		}
+		if len(sniffingSettings.DomainsExcluded) > 0 {
+			report.Warn(path+".domainsExcluded", tag, "destinations of excluded domains ", strings.Join(sniffingSettings.DomainsExcluded, ", "), " are overridden too, sing-box has no exclusion")
+		}
+	}
+	return option.InboundOptions{
+		SniffEnabled:             true,
+		SniffOverrideDestination: overrideDestination,
+	}
+}
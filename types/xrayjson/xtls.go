@@ -0,0 +1,108 @@
+package xrayjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	sjson "github.com/sagernet/sing-box/common/json"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/v2box"
+
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+type xtlsStreamConfig struct {
+	StreamSettings *struct {
+		XTLSSettings *conf.TLSConfig `json:"xtlsSettings"`
+	} `json:"streamSettings"`
+}
+
+func (c xtlsStreamConfig) settings() *conf.TLSConfig {
+	if c.StreamSettings == nil {
+		return nil
+	}
+	return c.StreamSettings.XTLSSettings
+}
+
+// parseXTLS decodes the XTLS settings of inbounds and outbounds by index, as StreamConfig no longer supports them.
+func parseXTLS(content []byte) ([]*conf.TLSConfig, []*conf.TLSConfig, error) {
+	var config struct {
+		Inbounds  []xtlsStreamConfig `json:"inbounds"`
+		Outbounds []xtlsStreamConfig `json:"outbounds"`
+	}
+	decoder := sjson.NewDecoder(sjson.NewCommentFilter(bytes.NewReader(content)))
+	err := decoder.Decode(&config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return common.Map(config.Inbounds, xtlsStreamConfig.settings), common.Map(config.Outbounds, xtlsStreamConfig.settings), nil
+}
+
+// migrateXTLS rewrites XTLS security into TLS and legacy XTLS flows before the config is built, as xray no longer builds them.
+// VLESS flows become xtls-rprx-vision, the only flow of sing-box, and trojan flows are removed,
+// neither is compatible with peers still on the legacy flows. Variants of xtls-rprx-vision such as
+// xtls-rprx-vision-udp443 only change client behavior, and become xtls-rprx-vision as well.
+func migrateXTLS(protocol string, streamSettings *conf.StreamConfig, xtlsSettings *conf.TLSConfig, settings *json.RawMessage, path string, tag string, report *v2box.Report) error {
+	if streamSettings != nil && strings.EqualFold(streamSettings.Security, "xtls") {
+		streamSettings.Security = "tls"
+		streamSettings.TLSSettings = xtlsSettings
+		report.Warn(path+".streamSettings.security", tag, "XTLS is migrated to TLS")
+	}
+	if settings == nil || !bytes.Contains(*settings, []byte("xtls-rprx-")) {
+		return nil
+	}
+	var content any
+	err := json.Unmarshal(*settings, &content)
+	if err != nil {
+		return v2box.WrapPathError("settings", err)
+	}
+	var flows []string
+	rewriteFlows(content, protocol, &flows)
+	if len(flows) == 0 {
+		return nil
+	}
+	newSettings, err := json.Marshal(content)
+	if err != nil {
+		return v2box.WrapPathError("settings", err)
+	}
+	*settings = newSettings
+	sort.Strings(flows)
+	for _, flow := range common.Uniq(flows) {
+		if protocol == "trojan" {
+			report.Warn(path+".settings", tag, "flow ", flow, " is not supported by trojan and is removed")
+		} else if strings.HasPrefix(flow, "xtls-rprx-vision-") {
+			report.Warn(path+".settings", tag, "flow ", flow, " is not supported by sing-box and is migrated to xtls-rprx-vision")
+		} else {
+			report.Add(v2box.SeverityError, path+".settings", tag, "flow ", flow, " is migrated to xtls-rprx-vision, which is not compatible with ", flow, " peers")
+		}
+	}
+	return nil
+}
+
+func rewriteFlows(content any, protocol string, flows *[]string) {
+	switch contentType := content.(type) {
+	case map[string]any:
+		for key, value := range contentType {
+			flow, isString := value.(string)
+			if key != "flow" || !isString {
+				rewriteFlows(value, protocol, flows)
+				continue
+			}
+			if !strings.HasPrefix(flow, "xtls-rprx-") || flow == "xtls-rprx-vision" {
+				continue
+			}
+			*flows = append(*flows, flow)
+			if protocol == "trojan" {
+				delete(contentType, key)
+			} else {
+				contentType[key] = "xtls-rprx-vision"
+			}
+		}
+	case []any:
+		for _, value := range contentType {
+			rewriteFlows(value, protocol, flows)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package xrayjson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/v2box"
+)
+
+func TestMigrateXTLSFlow(t *testing.T) {
+	options, report, err := Migrate([]byte(`{
+		"outbounds": [{
+			"protocol": "vless",
+			"tag": "proxy",
+			"settings": {"vnext": [{"address": "1.2.3.4", "port": 443, "users": [{"id": "b831381d-6324-4d53-ad4f-8cda48b30811", "encryption": "none", "flow": "xtls-rprx-direct"}]}]},
+			"streamSettings": {"security": "xtls", "xtlsSettings": {"serverName": "example.com"}}
+		}]
+	}`), v2box.MigrateOptions{}, log.StdLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(options.Outbounds) != 1 || options.Outbounds[0].VLESSOptions.Flow != "xtls-rprx-vision" {
+		t.Fatalf("expected a vless outbound with flow xtls-rprx-vision, got %v", options.Outbounds)
+	}
+	errors := report.Filter(v2box.SeverityError)
+	if len(errors) != 1 || errors[0].Path != "outbounds[0].settings" || !strings.Contains(errors[0].Reason, "xtls-rprx-direct") {
+		t.Errorf("expected the legacy flow to be reported as an error, got %v", errors)
+	}
+}
@@ -6,7 +6,27 @@ import (
 	"github.com/sagernet/sing/common/logger"
 )
 
-type Migration func(configuration []byte, logger logger.Logger) (option.Options, error)
+// DefaultMaxInboundPorts is the number of ports an inbound port range or list is expanded to at most by default.
+const DefaultMaxInboundPorts = 64
+
+// MigrateOptions controls how migrations complete the converted options.
+type MigrateOptions struct {
+	// DisableInjection stops adding the direct outbound and local DNS server that migrated elements depend on,
+	// elements requiring them are reported instead.
+	DisableInjection bool
+	// MaxInboundPorts limits the inbounds a port range or list is expanded into,
+	// larger ones are reduced to their first port. Zero means DefaultMaxInboundPorts.
+	MaxInboundPorts int
+}
+
+func (o MigrateOptions) InboundPortLimit() int {
+	if o.MaxInboundPorts <= 0 {
+		return DefaultMaxInboundPorts
+	}
+	return o.MaxInboundPorts
+}
+
+type Migration func(configuration []byte, options MigrateOptions, logger logger.Logger) (option.Options, Report, error)
 
 var (
 	migrationMap map[string]Migration
@@ -24,22 +44,29 @@ func Register(typeName string, versionString string, migration Migration) {
 	versionMap[typeName] = versionString
 }
 
-func Migrate(typeName string, configuration []byte, logger logger.Logger) (option.Options, error) {
+func Migrate(typeName string, configuration []byte, migrateOptions MigrateOptions, logger logger.Logger) (option.Options, Report, error) {
 	if typeName == "auto" {
-		for migrationType, migration := range migrationMap {
-			logger.Info("trying to migrate configuration as type ", migrationType)
-			options, err := migration(configuration, logger)
-			if err == nil {
-				return options, nil
+		var errors []error
+		for _, detection := range Detect(configuration) {
+			logger.Debug("trying to migrate configuration as ", detection)
+			options, report, err := migrationMap[detection.TypeName](configuration, migrateOptions, logger)
+			if err != nil {
+				errors = append(errors, E.Cause(err, detection.TypeName))
+				continue
 			}
+			logger.Info("detected configuration type ", detection)
+			return options, report, nil
+		}
+		if len(errors) == 0 {
+			return option.Options{}, Report{}, E.New("failed to detect configuration type")
 		}
-		return option.Options{}, E.New("failed to detect configuration type")
+		return option.Options{}, Report{}, E.Cause(E.Errors(errors...), "failed to detect configuration type")
 	}
 	migration, loaded := migrationMap[typeName]
 	if !loaded {
-		return option.Options{}, E.New("unknown configuration type: ", typeName)
+		return option.Options{}, Report{}, E.New("unknown configuration type: ", typeName)
 	}
-	return migration(configuration, logger)
+	return migration(configuration, migrateOptions, logger)
 }
 
 func Version(typeName string) string {